@@ -3,14 +3,16 @@ package main
 // 使用 http.Server 的 Shutdown 方法 实现优雅 重启
 import (
 	"context"
+	"flag"
 	"fmt"
 	"github.com/EGGYC/go-gin-example/models"
+	"github.com/EGGYC/go-gin-example/pkg/container"
+	"github.com/EGGYC/go-gin-example/pkg/health"
+	"github.com/EGGYC/go-gin-example/pkg/lifecycle"
 	"github.com/EGGYC/go-gin-example/pkg/logging"
 	"log"
 	"net/http"
 	"os"
-	"os/signal"
-	//"syscall"
 	"time"
 
 	"github.com/EGGYC/go-gin-example/pkg/setting"
@@ -18,36 +20,96 @@ import (
 )
 
 func main() {
-	setting.Setup()
-	models.Setup()
-	logging.Setup()
-	router := routers.InitRouter()
+	opts, args, err := setting.ParseFlags(os.Args[1:])
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		os.Exit(2)
+	}
+	if err := setting.Setup(opts); err != nil {
+		log.Fatal(err)
+	}
+	cfg := setting.Current()
+
+	if len(args) > 0 && args[0] == "migrate" {
+		db, err := models.Open(&cfg.Database)
+		if err != nil {
+			log.Fatalf("models.Open err: %v", err)
+		}
+		err = runMigrate(db, args[1:])
+		db.Close()
+		if err != nil {
+			log.Fatalf("migrate err: %v", err)
+		}
+		return
+	}
+
+	// 每个资源启动后立即登记关闭函数，关闭时按相反顺序执行
+	lc := lifecycle.New()
+	a, err := container.New(cfg, lc, nil)
+	if err != nil {
+		lc.Shutdown(context.Background())
+		log.Fatalf("container.New err: %v", err)
+	}
+
+	// log.Fatalf exits right away, so the resources opened so far are closed first
+	c, err := setupCron(a)
+	if err != nil {
+		lc.Shutdown(context.Background())
+		log.Fatalf("setupCron err: %v", err)
+	}
+	lc.Add("cron", func(ctx context.Context) error {
+		return stopCron(ctx, c)
+	})
+
+	if err := setupReload(lc, cfg); err != nil {
+		lc.Shutdown(context.Background())
+		log.Fatalf("setupReload err: %v", err)
+	}
+
+	router := routers.InitRouter(a)
 
 	s := &http.Server{
-		Addr:           fmt.Sprintf(":%d", setting.ServerSetting.HttpPort),
+		Addr:           fmt.Sprintf(":%d", cfg.Server.HttpPort),
 		Handler:        router,
-		ReadTimeout:    setting.ServerSetting.ReadTimeout,
-		WriteTimeout:   setting.ServerSetting.WriteTimeout,
+		ReadTimeout:    cfg.Server.ReadTimeout,
+		WriteTimeout:   cfg.Server.WriteTimeout,
 		MaxHeaderBytes: 1 << 20,
 	}
 
+	serveErr := make(chan error, 1)
 	go func() {
-		if err := s.ListenAndServe(); err != nil {
-			log.Printf("Listen: %s\n", err)
+		if err := s.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			serveErr <- err
 		}
 	}()
+	lc.Add("http server", func(ctx context.Context) error {
+		// fail /readyz first so the load balancer stops sending us new requests
+		health.SetShuttingDown()
+		select {
+		case <-time.After(cfg.Server.DrainDelay):
+		case <-ctx.Done():
+		}
+
+		return s.Shutdown(ctx)
+	})
 
-	quit := make(chan os.Signal)
-	signal.Notify(quit, os.Interrupt)
-	<-quit
+	listenErr := lc.Wait(serveErr)
+	if listenErr != nil {
+		logging.Error("Listen:", listenErr)
+	}
 
 	log.Println("Shutdown Server ...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
-	if err := s.Shutdown(ctx); err != nil {
+	if err := lc.Shutdown(ctx); err != nil {
 		log.Fatal("Server Shutdown:", err)
 	}
+	if listenErr != nil {
+		log.Fatal("Listen:", listenErr)
+	}
 
 	log.Println("Server exiting")
 }
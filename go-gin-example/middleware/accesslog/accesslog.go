@@ -0,0 +1,69 @@
+// Package accesslog 把每个请求写入 access 日志，替代写到 stdout 的 gin.Logger
+package accesslog
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/EGGYC/go-gin-example/middleware/jwt"
+	"github.com/EGGYC/go-gin-example/middleware/requestid"
+	"github.com/EGGYC/go-gin-example/pkg/logging"
+)
+
+// AccessLog must be registered before Recovery so requests that panic are logged with their 500
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		entry := &logging.AccessEntry{
+			Time:      start,
+			Method:    c.Request.Method,
+			Route:     c.FullPath(),
+			URI:       redactURI(c.Request.URL),
+			Proto:     c.Request.Proto,
+			Status:    c.Writer.Status(),
+			Latency:   time.Since(start),
+			Bytes:     c.Writer.Size(),
+			ClientIP:  c.ClientIP(),
+			RequestID: requestid.Get(c),
+			Referer:   c.Request.Referer(),
+			UserAgent: c.Request.UserAgent(),
+		}
+		if entry.Bytes < 0 {
+			entry.Bytes = 0
+		}
+		if claims := jwt.GetClaims(c); claims != nil {
+			entry.UserID = claims.UserID
+		}
+
+		logging.Access(entry)
+	}
+}
+
+// redactURI hides the token of [app] JwtTokenLookup = query and refresh tokens sent in the query
+func redactURI(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.RequestURI()
+	}
+
+	query := u.Query()
+	redacted := false
+	for _, key := range []string{"token", "refresh_token"} {
+		if _, ok := query[key]; ok {
+			query.Set(key, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return u.RequestURI()
+	}
+
+	r := *u
+	r.RawQuery = query.Encode()
+
+	return r.RequestURI()
+}
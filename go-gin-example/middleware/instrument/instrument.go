@@ -0,0 +1,62 @@
+// Package instrument 记录请求的 Prometheus 指标，并保护 /metrics
+package instrument
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/EGGYC/go-gin-example/pkg/app"
+	"github.com/EGGYC/go-gin-example/pkg/metrics"
+)
+
+// UNMATCHED is the route label of requests no route matched, their paths would
+// give every scanner probe its own series
+const UNMATCHED = "unmatched"
+
+// Metrics must be registered before Recovery so requests that panic are counted with their 500
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = UNMATCHED
+		}
+
+		code := metrics.NO_CODE
+		appG := app.Gin{C: c}
+		if errCode, ok := appG.Code(); ok {
+			code = strconv.Itoa(errCode)
+		}
+
+		metrics.ObserveHTTP(c.Request.Method, route, c.Writer.Status(), code, time.Since(start))
+	}
+}
+
+// RequireToken lets the scraper through when it sends "Authorization: Bearer <token>",
+// an empty token leaves the route open
+func RequireToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+
+		parts := strings.Fields(c.GetHeader("Authorization"))
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") ||
+			subtle.ConstantTimeCompare([]byte(parts[1]), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="metrics"`)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		c.Next()
+	}
+}
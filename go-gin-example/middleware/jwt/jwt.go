@@ -1,39 +1,99 @@
 package jwt
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/EGGYC/go-gin-example/pkg/app"
+	"github.com/EGGYC/go-gin-example/pkg/container"
 	"github.com/EGGYC/go-gin-example/pkg/e"
+	"github.com/EGGYC/go-gin-example/pkg/logging"
+	"github.com/EGGYC/go-gin-example/pkg/setting"
 	"github.com/EGGYC/go-gin-example/pkg/util"
+	"github.com/EGGYC/go-gin-example/service/auth_service"
 )
 
-func JWT() gin.HandlerFunc {
+// CLAIMS_KEY is the gin context key of the claims of a valid token
+const CLAIMS_KEY = "jwt_claims"
+
+// token sources listed in [app] JwtTokenLookup, they are tried in the configured order
+const (
+	LOOKUP_HEADER = "header"
+	LOOKUP_COOKIE = "cookie"
+	LOOKUP_QUERY  = "query"
+)
+
+// RFC 6750 error codes of the WWW-Authenticate header
+const (
+	errInvalidRequest = "invalid_request"
+	errInvalidToken   = "invalid_token"
+)
+
+const realm = "gin-blog"
+
+var errMalformedHeader = errors.New("malformed Authorization header")
+
+// JWT accepts the requests carrying an access token signed by a.Tokens that
+// a.Denylist hasn't revoked, issued since the last password change of the user
+func JWT(a *container.App) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var code int
 		var data interface{}
+		var authErr string
 
 		code = e.SUCCESS
-		token := c.Query("token")
-		if token == "" {
+		token, err := lookupToken(c, &a.Config.App)
+		if err != nil {
+			code = e.INVALID_PARAMS
+			authErr = errInvalidRequest
+		} else if token == "" {
 			code = e.INVALID_PARAMS
 		} else {
-			claims, err := util.ParseToken(token)
+			claims, err := a.Tokens.Parse(token)
 			if err != nil {
 				code = e.ERROR_AUTH_CHECK_TOKEN_FAIL
 			} else if time.Now().Unix() > claims.ExpiresAt {
 				code = e.ERROR_AUTH_CHECK_TOKEN_TIMEOUT
+			} else if claims.Type != util.TOKEN_ACCESS {
+				code = e.ERROR_AUTH_CHECK_TOKEN_FAIL
+			} else if revoked, err := a.Denylist.IsRevoked(claims.Id); err != nil {
+				logging.FromContext(c.Request.Context()).Warn("denylist.IsRevoked err:", err)
+				code = e.ERROR_AUTH_CHECK_TOKEN_FAIL
+			} else if revoked {
+				code = e.ERROR_AUTH_TOKEN_REVOKED
+			} else if current, err := (&auth_service.Auth{Service: a.Auth, ID: claims.UserID}).IsTokenCurrent(claims.Version); err != nil {
+				logging.FromContext(c.Request.Context()).Warn("auth_service.IsTokenCurrent err:", err)
+				code = e.ERROR_AUTH_CHECK_TOKEN_FAIL
+			} else if !current {
+				code = e.ERROR_AUTH_TOKEN_REVOKED
+			} else {
+				c.Set(CLAIMS_KEY, claims)
+
+				// entries of the request-scoped logger name the user from here on
+				ctx := c.Request.Context()
+				l := logging.FromContext(ctx).With("user_id", claims.UserID)
+				c.Request = c.Request.WithContext(logging.NewContext(ctx, l))
+			}
+
+			if code != e.SUCCESS {
+				authErr = errInvalidToken
 			}
 		}
 
 		if code != e.SUCCESS {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"code": code,
-				"msg":  e.GetMsg(code),
-				"data": data,
-			})
+			httpCode := http.StatusUnauthorized
+			if authErr == errInvalidRequest {
+				httpCode = http.StatusBadRequest
+			}
+
+			c.Header("WWW-Authenticate", authenticate(authErr, code))
+			appG := app.Gin{C: c}
+			appG.Response(httpCode, code, data)
 
 			c.Abort()
 			return
@@ -42,3 +102,68 @@ func JWT() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// GetClaims returns the claims stored by JWT, it is nil outside of routes using JWT
+func GetClaims(c *gin.Context) *util.Claims {
+	if claims, ok := c.Get(CLAIMS_KEY); ok {
+		return claims.(*util.Claims)
+	}
+
+	return nil
+}
+
+// lookupToken returns the first token found in the sources of JwtTokenLookup,
+// an empty token means the request carries no credentials at all
+func lookupToken(c *gin.Context, s *setting.App) (string, error) {
+	for _, source := range s.JwtTokenLookup {
+		var token string
+		switch source {
+		case LOOKUP_HEADER:
+			header := c.GetHeader("Authorization")
+			if header == "" {
+				continue
+			}
+
+			// other schemes such as Basic are not ours to reject, a header of only
+			// white space has no scheme at all
+			parts := strings.Fields(header)
+			if len(parts) == 0 || !strings.EqualFold(parts[0], "Bearer") {
+				continue
+			}
+			if len(parts) != 2 {
+				return "", errMalformedHeader
+			}
+			token = parts[1]
+		case LOOKUP_COOKIE:
+			token, _ = c.Cookie(s.JwtCookieName)
+		case LOOKUP_QUERY:
+			token = c.Query("token")
+		}
+
+		if token != "" {
+			return token, nil
+		}
+	}
+
+	return "", nil
+}
+
+// authenticate builds the RFC 6750 challenge, a request without credentials gets no error code.
+// error_description must be plain ASCII so it can't reuse the messages of pkg/e
+func authenticate(authErr string, code int) string {
+	if authErr == "" {
+		return fmt.Sprintf(`Bearer realm="%s"`, realm)
+	}
+
+	description := "The access token is invalid"
+	switch code {
+	case e.INVALID_PARAMS:
+		description = "The Authorization header is malformed"
+	case e.ERROR_AUTH_CHECK_TOKEN_TIMEOUT:
+		description = "The access token expired"
+	case e.ERROR_AUTH_TOKEN_REVOKED:
+		description = "The access token was revoked"
+	}
+
+	return fmt.Sprintf(`Bearer realm="%s", error="%s", error_description="%s"`, realm, authErr, description)
+}
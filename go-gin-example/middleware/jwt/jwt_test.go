@@ -0,0 +1,47 @@
+package jwt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/EGGYC/go-gin-example/pkg/setting"
+)
+
+func TestLookupTokenHeader(t *testing.T) {
+	s := &setting.App{JwtTokenLookup: []string{LOOKUP_HEADER, LOOKUP_COOKIE}, JwtCookieName: "token"}
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+		err    error
+	}{
+		{"bearer", "Bearer abc", "abc", nil},
+		{"scheme is case insensitive", "bearer abc", "abc", nil},
+		{"extra white space", "  Bearer \t abc ", "abc", nil},
+		{"other scheme falls back to the cookie", "Basic dXNlcjpwYXNz", "from-cookie", nil},
+		{"no header falls back to the cookie", "", "from-cookie", nil},
+		{"only white space falls back to the cookie", " ", "from-cookie", nil},
+		{"only a vertical tab falls back to the cookie", "\v", "from-cookie", nil},
+		{"bearer without token", "Bearer", "", errMalformedHeader},
+		{"bearer with two tokens", "Bearer abc def", "", errMalformedHeader},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("Authorization", tt.header)
+			}
+			c.Request.AddCookie(&http.Cookie{Name: "token", Value: "from-cookie"})
+
+			got, err := lookupToken(c, s)
+			if got != tt.want || err != tt.err {
+				t.Fatalf("lookupToken with %q = %q, %v, want %q, %v", tt.header, got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+// Package permission 基于角色的权限控制，必须放在 jwt.JWT() 之后使用
+package permission
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/EGGYC/go-gin-example/middleware/jwt"
+	"github.com/EGGYC/go-gin-example/models"
+	"github.com/EGGYC/go-gin-example/pkg/app"
+	"github.com/EGGYC/go-gin-example/pkg/e"
+)
+
+// permissions declared by the routes
+const (
+	TAG_READ  = "tag:read"
+	TAG_WRITE = "tag:write"
+
+	ARTICLE_READ   = "article:read"
+	ARTICLE_CREATE = "article:create"
+	// ARTICLE_EDIT_OWN allows editing and deleting the articles the user created
+	ARTICLE_EDIT_OWN = "article:edit_own"
+	// ARTICLE_EDIT allows editing and deleting any article
+	ARTICLE_EDIT = "article:edit"
+
+	USER_MANAGE = "user:manage"
+)
+
+var reader = []string{TAG_READ, ARTICLE_READ}
+var author = append([]string{ARTICLE_CREATE, ARTICLE_EDIT_OWN}, reader...)
+var editor = append([]string{TAG_WRITE, ARTICLE_EDIT}, author...)
+var admin = append([]string{USER_MANAGE}, editor...)
+
+// rolePermissions grants every role the permissions of the roles below it
+var rolePermissions = map[string]map[string]bool{
+	models.ROLE_READER: toSet(reader),
+	models.ROLE_AUTHOR: toSet(author),
+	models.ROLE_EDITOR: toSet(editor),
+	models.ROLE_ADMIN:  toSet(admin),
+}
+
+// Require lets the request through when the current user has any of perms
+func Require(perms ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, perm := range perms {
+			if Has(c, perm) {
+				c.Next()
+				return
+			}
+		}
+
+		appG := app.Gin{C: c}
+		appG.Response(http.StatusForbidden, e.ERROR_AUTH_PERMISSION, nil)
+
+		c.Abort()
+	}
+}
+
+// Has reports whether the roles in the token of the request grant perm
+func Has(c *gin.Context, perm string) bool {
+	claims := jwt.GetClaims(c)
+	if claims == nil {
+		return false
+	}
+
+	for _, role := range claims.Roles {
+		if rolePermissions[role][perm] {
+			return true
+		}
+	}
+
+	return false
+}
+
+func toSet(perms []string) map[string]bool {
+	set := make(map[string]bool, len(perms))
+	for _, perm := range perms {
+		set[perm] = true
+	}
+
+	return set
+}
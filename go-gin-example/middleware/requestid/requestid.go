@@ -0,0 +1,63 @@
+// Package requestid 给每个请求分配 ID，并把带有该 ID 的 logger 放入请求的 context
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/EGGYC/go-gin-example/pkg/logging"
+)
+
+// HEADER carries an ID assigned by a proxy in front of us, the response echoes the ID
+const HEADER = "X-Request-ID"
+
+// KEY is the gin context key of the request ID
+const KEY = "request_id"
+
+// RequestID gives every request an ID and a logger derived from base that carries it
+func RequestID(base *logging.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(HEADER)
+		if !isValid(id) {
+			id = newID()
+		}
+
+		c.Set(KEY, id)
+		c.Header(HEADER, id)
+
+		l := base.With("request_id", id)
+		c.Request = c.Request.WithContext(logging.NewContext(c.Request.Context(), l))
+
+		c.Next()
+	}
+}
+
+// Get returns the ID of the request, it is empty outside of RequestID
+func Get(c *gin.Context) string {
+	return c.GetString(KEY)
+}
+
+// isValid only accepts short IDs made of safe characters, so they can't forge log lines
+func isValid(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/EGGYC/go-gin-example/models"
+	"github.com/EGGYC/go-gin-example/pkg/util"
+)
+
+const migrateUsage = "usage: go-gin-example [-config file] [-env name] migrate up|down|status|admin username [password]"
+
+// runMigrate handles the `migrate` subcommand
+func runMigrate(db *models.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(migrateUsage)
+	}
+	if args[0] == "admin" {
+		if len(args) != 2 && len(args) != 3 {
+			return fmt.Errorf(migrateUsage)
+		}
+		return createAdmin(db, args[1], args[2:]...)
+	}
+	if len(args) != 1 {
+		return fmt.Errorf(migrateUsage)
+	}
+
+	m, err := db.NewMigrator()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		done, err := m.Up()
+		for _, migration := range done {
+			fmt.Printf("applied %d %s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	case "down":
+		migration, err := m.Down()
+		if err != nil {
+			return err
+		}
+		if migration == nil {
+			fmt.Println("no applied migrations")
+			return nil
+		}
+		fmt.Printf("rolled back %d %s\n", migration.Version, migration.Name)
+	case "status":
+		list, err := m.Status()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED ON")
+		for _, s := range list {
+			appliedOn := "pending"
+			if s.Applied {
+				appliedOn = time.Unix(int64(s.AppliedOn), 0).Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedOn)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf(migrateUsage)
+	}
+
+	return nil
+}
+
+// createAdmin gives username the admin role. The account is created when a password
+// is given, otherwise it must already exist. Nothing else creates an admin, the
+// migrations must be applied first
+func createAdmin(db *models.DB, username string, password ...string) error {
+	auth, err := db.GetAuthByUsername(username)
+	if err != nil {
+		return err
+	}
+
+	if len(password) == 0 {
+		if auth == nil {
+			return fmt.Errorf("no account %q, give a password to create it", username)
+		}
+		if err := db.EditAuthRole(auth.ID, models.ROLE_ADMIN); err != nil {
+			return err
+		}
+		fmt.Printf("%s is now an admin\n", username)
+		return nil
+	}
+
+	if auth != nil {
+		return fmt.Errorf("account %q exists, leave out the password to promote it", username)
+	}
+	// the same bounds as /auth/register
+	if n := len(password[0]); n < 6 || n > 50 {
+		return fmt.Errorf("the password must be 6 to 50 characters")
+	}
+	hash, err := util.HashPassword(password[0])
+	if err != nil {
+		return err
+	}
+	if err := db.AddAuth(username, hash, models.ROLE_ADMIN); err != nil {
+		return err
+	}
+	fmt.Printf("created admin %s\n", username)
+
+	return nil
+}
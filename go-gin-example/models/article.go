@@ -20,9 +20,14 @@ type Article struct {
 }
 
 // ExistArticleByID checks if an article exists based on ID
-func ExistArticleByID(id int) (bool, error) {
+func (d *DB) ExistArticleByID(id int) (bool, error) {
+	return ExistArticleByIDTx(d.conn, id)
+}
+
+// ExistArticleByIDTx checks if an article exists based on ID inside a transaction
+func ExistArticleByIDTx(tx *gorm.DB, id int) (bool, error) {
 	var article Article
-	err := db.Select("id").Where("id = ? AND deleted_on = ? ", id, 0).First(&article).Error
+	err := tx.Select("id").Where("id = ? AND deleted_on = ? ", id, 0).First(&article).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return false, err
 	}
@@ -34,20 +39,52 @@ func ExistArticleByID(id int) (bool, error) {
 	return false, nil
 }
 
+// IsArticleDeletedTx checks if the ID belongs to a soft-deleted article that wasn't
+// purged yet, the ID is still taken then
+func IsArticleDeletedTx(tx *gorm.DB, id int) (bool, error) {
+	var article Article
+	err := tx.Unscoped().Select("id").Where("id = ? AND deleted_on != ? ", id, 0).First(&article).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return false, err
+	}
+
+	return article.ID > 0, nil
+}
+
 // GetArticleTotal gets the total number of articles based on the constraints
-func GetArticleTotal(maps interface{}) (int, error) {
+func (d *DB) GetArticleTotal(maps interface{}) (int, error) {
 	var count int
-	if err := db.Model(&Article{}).Where(maps).Count(&count).Error; err != nil {
+	if err := d.conn.Model(&Article{}).Where(maps).Count(&count).Error; err != nil {
 		return 0, err
 	}
 
 	return count, nil
 }
 
-// GetArticles gets a list of articles based on paging constraints
-func GetArticles(pageNum int, pageSize int, maps interface{}) ([]*Article, error) {
+// GetArticles gets a list of articles based on paging constraints, a pageSize of 0 gets all of them
+func (d *DB) GetArticles(pageNum int, pageSize int, maps interface{}) ([]*Article, error) {
+	var (
+		articles []*Article
+		err      error
+	)
+
+	if pageSize > 0 {
+		err = d.conn.Preload("Tag").Where(maps).Offset(pageNum).Limit(pageSize).Find(&articles).Error
+	} else {
+		err = d.conn.Preload("Tag").Where(maps).Find(&articles).Error
+	}
+
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	return articles, nil
+}
+
+// GetArticlesByIDs gets the live articles of ids in no particular order
+func (d *DB) GetArticlesByIDs(ids []int) ([]*Article, error) {
 	var articles []*Article
-	err := db.Preload("Tag").Where(maps).Offset(pageNum).Limit(pageSize).Find(&articles).Error
+	err := d.conn.Preload("Tag").Where("id IN (?) AND deleted_on = ?", ids, 0).Find(&articles).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
@@ -55,15 +92,44 @@ func GetArticles(pageNum int, pageSize int, maps interface{}) ([]*Article, error
 	return articles, nil
 }
 
+// ArticleScore is an article ranked by SearchArticles
+type ArticleScore struct {
+	ID    int
+	Score float64
+}
+
+// articleMatch is the FULLTEXT search of the add_article_fulltext migration
+const articleMatch = "MATCH (title, `desc`, content) AGAINST (? IN NATURAL LANGUAGE MODE)"
+
+// SearchArticles ranks the live articles matching query with the FULLTEXT index of
+// MySQL, a limit of 0 gets every match. total counts the matches of every page
+func (d *DB) SearchArticles(query string, offset, limit int) (scores []ArticleScore, total int, err error) {
+	where := "deleted_on = ? AND " + articleMatch
+	if err := d.conn.Model(&Article{}).Where(where, 0, query).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	db := d.conn.Model(&Article{}).Select("id, "+articleMatch+" AS score", query).
+		Where(where, 0, query).Order("score DESC, id DESC")
+	if limit > 0 {
+		db = db.Offset(offset).Limit(limit)
+	}
+	if err := db.Scan(&scores).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return scores, total, nil
+}
+
 // GetArticle Get a single article based on ID
-func GetArticle(id int) (*Article, error) {
+func (d *DB) GetArticle(id int) (*Article, error) {
 	var article Article
-	err := db.Where("id = ? AND deleted_on = ? ", id, 0).First(&article).Error
+	err := d.conn.Where("id = ? AND deleted_on = ? ", id, 0).First(&article).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
 
-	err = db.Model(&article).Related(&article.Tag).Error
+	err = d.conn.Model(&article).Related(&article.Tag).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
@@ -71,17 +137,38 @@ func GetArticle(id int) (*Article, error) {
 	return &article, nil
 }
 
+// GetArticleIDsByTagID gets the IDs of the live articles of a tag
+func (d *DB) GetArticleIDsByTagID(tagID int) ([]int, error) {
+	var ids []int
+	err := d.conn.Model(&Article{}).Where("tag_id = ? AND deleted_on = ?", tagID, 0).Pluck("id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
 // EditArticle modify a single article
-func EditArticle(id int, data interface{}) error {
-	if err := db.Model(&Article{}).Where("id = ? AND deleted_on = ? ", id, 0).Updates(data).Error; err != nil {
+func (d *DB) EditArticle(id int, data interface{}) error {
+	return EditArticleTx(d.conn, id, data)
+}
+
+// EditArticleTx modify a single article inside a transaction
+func EditArticleTx(tx *gorm.DB, id int, data interface{}) error {
+	if err := tx.Model(&Article{}).Where("id = ? AND deleted_on = ? ", id, 0).Updates(data).Error; err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// AddArticle add a single article
-func AddArticle(data map[string]interface{}) error {
+// AddArticle add a single article and returns its ID
+func (d *DB) AddArticle(data map[string]interface{}) (int, error) {
+	return AddArticleTx(d.conn, data)
+}
+
+// AddArticleTx add a single article inside a transaction, an optional "id" keeps the given ID
+func AddArticleTx(tx *gorm.DB, data map[string]interface{}) (int, error) {
 	article := Article{
 		TagID:         data["tag_id"].(int),
 		Title:         data["title"].(string),
@@ -91,16 +178,19 @@ func AddArticle(data map[string]interface{}) error {
 		State:         data["state"].(int),
 		CoverImageUrl: data["cover_image_url"].(string),
 	}
-	if err := db.Create(&article).Error; err != nil {
-		return err
+	if id, ok := data["id"].(int); ok {
+		article.ID = id
+	}
+	if err := tx.Create(&article).Error; err != nil {
+		return 0, err
 	}
 
-	return nil
+	return article.ID, nil
 }
 
 // DeleteArticle delete a single article
-func DeleteArticle(id int) error {
-	if err := db.Where("id = ?", id).Delete(Article{}).Error; err != nil {
+func (d *DB) DeleteArticle(id int) error {
+	if err := d.conn.Where("id = ?", id).Delete(Article{}).Error; err != nil {
 		return err
 	}
 
@@ -108,14 +198,24 @@ func DeleteArticle(id int) error {
 }
 
 // CleanAllArticle clear all article
-func CleanAllArticle() error {
-	if err := db.Unscoped().Where("deleted_on != ? ", 0).Delete(&Article{}).Error; err != nil {
+func (d *DB) CleanAllArticle() error {
+	if err := d.conn.Unscoped().Where("deleted_on != ? ", 0).Delete(&Article{}).Error; err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// CleanArticlesDeletedBefore hard deletes the articles soft deleted before the unix time
+func (d *DB) CleanArticlesDeletedBefore(before int64) (int64, error) {
+	result := d.conn.Unscoped().Where("deleted_on != ? AND deleted_on < ? ", 0, before).Delete(&Article{})
+	if err := result.Error; err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected, nil
+}
+
 //package models
 //
 //import "github.com/jinzhu/gorm"
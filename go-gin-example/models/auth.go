@@ -1,17 +1,75 @@
 package models
 
-import "github.com/jinzhu/gorm"
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+)
+
+// roles of an account, see middleware/permission for what each one may do
+const (
+	ROLE_ADMIN  = "admin"
+	ROLE_EDITOR = "editor"
+	ROLE_AUTHOR = "author"
+	ROLE_READER = "reader"
+)
 
 type Auth struct {
 	ID       int    `gorm:"primary_key" json:"id"`
 	Username string `json:"username"`
-	Password string `json:"password"`
+	Password string `json:"-"`
+	Role     string `json:"role"`
+	// TokenVersion is carried by the tokens of the account, changing it revokes them
+	TokenVersion int `json:"-"`
+}
+
+// ErrLastAdmin is returned by EditAuthRole when the change would leave no admin
+var ErrLastAdmin = errors.New("models: the last admin can't lose the role")
+
+// IsRole checks role is one of the known roles
+func IsRole(role string) bool {
+	switch role {
+	case ROLE_ADMIN, ROLE_EDITOR, ROLE_AUTHOR, ROLE_READER:
+		return true
+	}
+
+	return false
+}
+
+// GetAuth gets an account by ID, it returns nil when there is none
+func (d *DB) GetAuth(id int) (*Auth, error) {
+	var auth Auth
+	err := d.conn.Where("id = ?", id).First(&auth).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	if auth.ID > 0 {
+		return &auth, nil
+	}
+
+	return nil, nil
+}
+
+// GetAuthByUsername gets an account by username, it returns nil when there is none
+func (d *DB) GetAuthByUsername(username string) (*Auth, error) {
+	var auth Auth
+	err := d.conn.Where("username = ?", username).First(&auth).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	if auth.ID > 0 {
+		return &auth, nil
+	}
+
+	return nil, nil
 }
 
-// CheckAuth checks if authentication information exists
-func CheckAuth(username, password string) (bool, error) {
+// ExistAuthByUsername checks if the username is taken
+func (d *DB) ExistAuthByUsername(username string) (bool, error) {
 	var auth Auth
-	err := db.Select("id").Where(Auth{Username: username, Password: password}).First(&auth).Error
+	err := d.conn.Select("id").Where("username = ?", username).First(&auth).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return false, err
 	}
@@ -22,3 +80,65 @@ func CheckAuth(username, password string) (bool, error) {
 
 	return false, nil
 }
+
+// AddAuth adds an account, password must already be hashed
+func (d *DB) AddAuth(username, password, role string) error {
+	auth := Auth{
+		Username: username,
+		Password: password,
+		Role:     role,
+	}
+	if err := d.conn.Create(&auth).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// EditAuthPassword replaces the password of an account, password must already be hashed
+func (d *DB) EditAuthPassword(id int, password string) error {
+	if err := d.conn.Model(&Auth{}).Where("id = ?", id).Update("password", password).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ChangeAuthPassword replaces the password of an account and revokes the tokens issued
+// until now, password must already be hashed
+func (d *DB) ChangeAuthPassword(id int, password string) error {
+	err := d.conn.Model(&Auth{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"password":      password,
+		"token_version": gorm.Expr("token_version + 1"),
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// EditAuthRole changes the role of an account, it returns ErrLastAdmin rather than
+// take the role from the only admin
+func (d *DB) EditAuthRole(id int, role string) error {
+	return d.Transaction(func(tx *gorm.DB) error {
+		if role != ROLE_ADMIN {
+			// MySQL locks the admins so two of them can't demote each other at once,
+			// SQLite already serializes the transactions writing
+			query := tx
+			if tx.Dialect().GetName() == DIALECT_MYSQL {
+				query = tx.Set("gorm:query_option", "FOR UPDATE")
+			}
+
+			var admins []Auth
+			if err := query.Select("id").Where("role = ?", ROLE_ADMIN).Find(&admins).Error; err != nil {
+				return err
+			}
+			if len(admins) == 1 && admins[0].ID == id {
+				return ErrLastAdmin
+			}
+		}
+
+		return tx.Model(&Auth{}).Where("id = ?", id).Update("role", role).Error
+	})
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+
+	"github.com/EGGYC/go-gin-example/pkg/setting"
+)
+
+const (
+	DIALECT_MYSQL  = "mysql"
+	DIALECT_SQLITE = "sqlite3"
+
+	// SQLITE_MEMORY is the database name that keeps a SQLite database in memory
+	SQLITE_MEMORY = ":memory:"
+)
+
+// dialect describes how a storage backend is opened by gorm
+type dialect struct {
+	// Name is the dialect name registered in gorm
+	Name string
+	// DSN builds the data source name from the [database] section
+	DSN func(s *setting.Database) string
+	// MaxOpenConns limits the connection pool, zero keeps the default
+	MaxOpenConns func(s *setting.Database) int
+}
+
+// dialects maps the [database] Type setting to a storage backend
+var dialects = map[string]*dialect{
+	"mysql": {
+		Name: DIALECT_MYSQL,
+		DSN:  mysqlDSN,
+	},
+	"sqlite3": {
+		Name:         DIALECT_SQLITE,
+		DSN:          sqliteDSN,
+		MaxOpenConns: sqliteMaxOpenConns,
+	},
+	"sqlite": {
+		Name:         DIALECT_SQLITE,
+		DSN:          sqliteDSN,
+		MaxOpenConns: sqliteMaxOpenConns,
+	},
+}
+
+// getDialect returns the storage backend for the configured database type
+func getDialect(typ string) (*dialect, error) {
+	d, ok := dialects[strings.ToLower(typ)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database type: %q", typ)
+	}
+
+	return d, nil
+}
+
+// mysqlDSN user:password@tcp(host)/name
+func mysqlDSN(s *setting.Database) string {
+	return fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8&parseTime=True&loc=Local",
+		s.User,
+		s.Password,
+		s.Host,
+		s.Name)
+}
+
+// sqliteDSN uses Name as the database file, or ":memory:" for an in-memory database
+func sqliteDSN(s *setting.Database) string {
+	if s.Name == "" || s.Name == SQLITE_MEMORY {
+		return SQLITE_MEMORY
+	}
+	if strings.Contains(s.Name, "?") {
+		return s.Name
+	}
+
+	// wait for the write lock instead of failing with "database is locked"
+	return s.Name + "?_busy_timeout=5000"
+}
+
+// sqliteMaxOpenConns every connection to ":memory:" opens a new empty database,
+// so an in-memory database must live on a single connection
+func sqliteMaxOpenConns(s *setting.Database) int {
+	if sqliteDSN(s) == SQLITE_MEMORY {
+		return 1
+	}
+
+	return 0
+}
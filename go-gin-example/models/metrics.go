@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/EGGYC/go-gin-example/pkg/metrics"
+)
+
+const metricsStartKey = "metrics:start_time"
+
+// registerMetricsCallbacks times every gorm operation, from before its transaction
+// begins to after it commits. Raw Exec calls don't go through callbacks
+func registerMetricsCallbacks(conn *gorm.DB) {
+	cb := conn.Callback()
+
+	cb.Create().Before("gorm:begin_transaction").Register("metrics:start_create", startTimerCallback)
+	cb.Create().After("gorm:commit_or_rollback_transaction").Register("metrics:observe_create", observeCallback("create"))
+
+	cb.Update().Before("gorm:begin_transaction").Register("metrics:start_update", startTimerCallback)
+	cb.Update().After("gorm:commit_or_rollback_transaction").Register("metrics:observe_update", observeCallback("update"))
+
+	cb.Delete().Before("gorm:begin_transaction").Register("metrics:start_delete", startTimerCallback)
+	cb.Delete().After("gorm:commit_or_rollback_transaction").Register("metrics:observe_delete", observeCallback("delete"))
+
+	cb.Query().Before("gorm:query").Register("metrics:start_query", startTimerCallback)
+	cb.Query().After("gorm:after_query").Register("metrics:observe_query", observeCallback("query"))
+
+	cb.RowQuery().Before("gorm:row_query").Register("metrics:start_row_query", startTimerCallback)
+	cb.RowQuery().After("gorm:row_query").Register("metrics:observe_row_query", observeCallback("row_query"))
+}
+
+func startTimerCallback(scope *gorm.Scope) {
+	scope.InstanceSet(metricsStartKey, time.Now())
+}
+
+func observeCallback(operation string) func(scope *gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		value, ok := scope.InstanceGet(metricsStartKey)
+		if !ok {
+			return
+		}
+
+		metrics.ObserveDB(operation, scope.TableName(), time.Since(value.(time.Time)))
+	}
+}
@@ -0,0 +1,261 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/EGGYC/go-gin-example/pkg/migrate"
+)
+
+// migrations is the schema history, append new versions and never edit applied ones.
+// Each migration keeps its own snapshot of the tables so later changes to the models
+// don't rewrite history.
+var migrations = []*migrate.Migration{
+	{
+		Version: 1,
+		Name:    "create_blog_tables",
+		Up:      createBlogTablesUp,
+		Down:    createBlogTablesDown,
+	},
+	{
+		Version: 2,
+		Name:    "seed_default_auth",
+		Up:      seedDefaultAuthUp,
+		Down:    seedDefaultAuthDown,
+	},
+	{
+		Version: 3,
+		Name:    "hash_auth_password",
+		Up:      hashAuthPasswordUp,
+		Down:    hashAuthPasswordDown,
+	},
+	{
+		Version: 4,
+		Name:    "add_auth_role",
+		Up:      addAuthRoleUp,
+		Down:    addAuthRoleDown,
+	},
+	{
+		Version: 5,
+		Name:    "add_article_fulltext",
+		Up:      addArticleFulltextUp,
+		Down:    addArticleFulltextDown,
+	},
+	{
+		Version: 6,
+		Name:    "demote_seeded_auth",
+		Up:      demoteSeededAuthUp,
+		Down:    demoteSeededAuthDown,
+	},
+	{
+		Version: 7,
+		Name:    "add_auth_token_version",
+		Up:      addAuthTokenVersionUp,
+		Down:    addAuthTokenVersionDown,
+	},
+}
+
+// NewMigrator returns a migrator bound to the database
+func (d *DB) NewMigrator() (*migrate.Migrator, error) {
+	return migrate.NewMigrator(d.conn, migrations)
+}
+
+// tablePrefixKey holds the [database] TablePrefix in the settings of the connection,
+// the snapshot tables of the migrations read it from there
+const tablePrefixKey = "blog:table_prefix"
+
+// tableName adds the prefix of the connection to a table name
+func tableName(tx *gorm.DB, name string) string {
+	prefix, _ := tx.Get(tablePrefixKey)
+	s, _ := prefix.(string)
+	return s + name
+}
+
+// createTable creates the table for a snapshot struct unless it already exists,
+// so databases that were built from the old blog.sql dump can be baselined
+func createTable(tx *gorm.DB, value interface{}) error {
+	if tx.HasTable(value) {
+		return nil
+	}
+	if tx.Dialect().GetName() == DIALECT_MYSQL {
+		tx = tx.Set("gorm:table_options", "ENGINE=InnoDB DEFAULT CHARSET=utf8")
+	}
+
+	return tx.CreateTable(value).Error
+}
+
+type tagV1 struct {
+	ID         int    `gorm:"primary_key"`
+	Name       string `gorm:"type:varchar(100);default:''"`
+	CreatedOn  int    `gorm:"default:0"`
+	CreatedBy  string `gorm:"type:varchar(100);default:''"`
+	ModifiedOn int    `gorm:"default:0"`
+	ModifiedBy string `gorm:"type:varchar(100);default:''"`
+	DeletedOn  int    `gorm:"default:0"`
+	State      int    `gorm:"type:tinyint(3);default:1"`
+}
+
+func (tagV1) TableName(tx *gorm.DB) string { return tableName(tx, "tag") }
+
+type articleV1 struct {
+	ID            int    `gorm:"primary_key"`
+	TagID         int    `gorm:"default:0"`
+	Title         string `gorm:"type:varchar(100);default:''"`
+	Desc          string `gorm:"type:varchar(255);default:''"`
+	Content       string `gorm:"type:text"`
+	CoverImageUrl string `gorm:"type:varchar(255);default:''"`
+	CreatedOn     int    `gorm:"default:0"`
+	CreatedBy     string `gorm:"type:varchar(100);default:''"`
+	ModifiedOn    int    `gorm:"default:0"`
+	ModifiedBy    string `gorm:"type:varchar(255);default:''"`
+	DeletedOn     int    `gorm:"default:0"`
+	State         int    `gorm:"type:tinyint(3);default:1"`
+}
+
+func (articleV1) TableName(tx *gorm.DB) string { return tableName(tx, "article") }
+
+type authV1 struct {
+	ID       int    `gorm:"primary_key"`
+	Username string `gorm:"type:varchar(50);default:''"`
+	Password string `gorm:"type:varchar(50);default:''"`
+}
+
+func (authV1) TableName(tx *gorm.DB) string { return tableName(tx, "auth") }
+
+func createBlogTablesUp(tx *gorm.DB) error {
+	for _, value := range []interface{}{&tagV1{}, &articleV1{}, &authV1{}} {
+		if err := createTable(tx, value); err != nil {
+			return err
+		}
+	}
+
+	article := articleV1{}
+	return tx.Model(&article).AddIndex("idx_"+article.TableName(tx)+"_tag_id", "tag_id").Error
+}
+
+func createBlogTablesDown(tx *gorm.DB) error {
+	return tx.DropTableIfExists(&articleV1{}, &tagV1{}, &authV1{}).Error
+}
+
+// seedDefaultAuthUp keeps the test/test123 account that blog.sql used to insert
+func seedDefaultAuthUp(tx *gorm.DB) error {
+	var count int
+	if err := tx.Model(&authV1{}).Where("username = ?", "test").Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return tx.Create(&authV1{Username: "test", Password: "test123"}).Error
+}
+
+func seedDefaultAuthDown(tx *gorm.DB) error {
+	return tx.Where("username = ?", "test").Delete(&authV1{}).Error
+}
+
+// hashAuthPasswordUp makes room for bcrypt hashes and keeps usernames unique.
+// SQLite doesn't enforce varchar lengths, only MySQL needs the column widened
+func hashAuthPasswordUp(tx *gorm.DB) error {
+	auth := authV1{}
+	if tx.Dialect().GetName() == DIALECT_MYSQL {
+		if err := tx.Model(&auth).ModifyColumn("password", "varchar(255) DEFAULT ''").Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&auth).AddUniqueIndex("uix_"+auth.TableName(tx)+"_username", "username").Error
+}
+
+// hashAuthPasswordDown can't shrink the column back while it holds hashes
+func hashAuthPasswordDown(tx *gorm.DB) error {
+	auth := authV1{}
+	return tx.Model(&auth).RemoveIndex("uix_" + auth.TableName(tx) + "_username").Error
+}
+
+// addAuthRoleUp gives every existing account the reader role, except the seeded
+// test account that could do everything before roles existed
+func addAuthRoleUp(tx *gorm.DB) error {
+	auth := authV1{}
+	if !tx.Dialect().HasColumn(auth.TableName(tx), "role") {
+		sql := fmt.Sprintf("ALTER TABLE %s ADD role varchar(20) NOT NULL DEFAULT 'reader'",
+			tx.Dialect().Quote(auth.TableName(tx)))
+		if err := tx.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Table(auth.TableName(tx)).Where("username = ?", "test").Update("role", "admin").Error
+}
+
+// addAuthRoleDown leaves the column in SQLite, the bundled version can't drop columns
+// and the default keeps it harmless
+func addAuthRoleDown(tx *gorm.DB) error {
+	if tx.Dialect().GetName() != DIALECT_MYSQL {
+		return nil
+	}
+
+	return tx.Model(&authV1{}).DropColumn("role").Error
+}
+
+// articleFulltextIndex backs [search] Type = mysql, the ngram parser splits Chinese
+// into pairs of characters since it isn't separated by spaces
+func articleFulltextIndex(tx *gorm.DB) string {
+	return "ftx_" + articleV1{}.TableName(tx) + "_text"
+}
+
+// addArticleFulltextUp only indexes MySQL, SQLite has no FULLTEXT and uses the memory index
+func addArticleFulltextUp(tx *gorm.DB) error {
+	article := articleV1{}
+	if tx.Dialect().GetName() != DIALECT_MYSQL || tx.Dialect().HasIndex(article.TableName(tx), articleFulltextIndex(tx)) {
+		return nil
+	}
+
+	sql := fmt.Sprintf("ALTER TABLE %s ADD FULLTEXT INDEX %s (title, `desc`, content) WITH PARSER ngram",
+		tx.Dialect().Quote(article.TableName(tx)), tx.Dialect().Quote(articleFulltextIndex(tx)))
+	return tx.Exec(sql).Error
+}
+
+func addArticleFulltextDown(tx *gorm.DB) error {
+	if tx.Dialect().GetName() != DIALECT_MYSQL {
+		return nil
+	}
+
+	return tx.Model(&articleV1{}).RemoveIndex(articleFulltextIndex(tx)).Error
+}
+
+// demoteSeededAuthUp takes the admin role back from the seeded test account, its
+// password is public. The first admin is created with `migrate admin` instead
+func demoteSeededAuthUp(tx *gorm.DB) error {
+	auth := authV1{}
+	return tx.Table(auth.TableName(tx)).Where("username = ? AND role = ?", "test", "admin").Update("role", "reader").Error
+}
+
+// demoteSeededAuthDown doesn't promote the account again, an admin with a public
+// password isn't worth restoring
+func demoteSeededAuthDown(tx *gorm.DB) error {
+	return nil
+}
+
+// addAuthTokenVersionUp counts the password changes of an account, a token carries
+// the count it was issued with and is rejected once it changes
+func addAuthTokenVersionUp(tx *gorm.DB) error {
+	auth := authV1{}
+	if tx.Dialect().HasColumn(auth.TableName(tx), "token_version") {
+		return nil
+	}
+
+	sql := fmt.Sprintf("ALTER TABLE %s ADD token_version int NOT NULL DEFAULT 0",
+		tx.Dialect().Quote(auth.TableName(tx)))
+	return tx.Exec(sql).Error
+}
+
+// addAuthTokenVersionDown leaves the column in SQLite like addAuthRoleDown
+func addAuthTokenVersionDown(tx *gorm.DB) error {
+	if tx.Dialect().GetName() != DIALECT_MYSQL {
+		return nil
+	}
+
+	return tx.Model(&authV1{}).DropColumn("token_version").Error
+}
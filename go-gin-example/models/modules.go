@@ -1,18 +1,19 @@
 package models
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"path/filepath"
+	"strings"
 
 	"github.com/jinzhu/gorm"
-	_ "github.com/jinzhu/gorm/dialects/mysql"
 
+	"github.com/EGGYC/go-gin-example/pkg/file"
+	"github.com/EGGYC/go-gin-example/pkg/migrate"
 	"github.com/EGGYC/go-gin-example/pkg/setting"
 	"time"
 )
 
-var db *gorm.DB
-
 type Model struct {
 	ID         int `gorm:"primary_key" json:"id"`
 	CreatedOn  int `json:"created_on"`
@@ -20,34 +21,119 @@ type Model struct {
 	DeletedOn  int `json:"deleted_on"`
 }
 
-// Setup initializes the database instance
-func Setup() {
-	var err error
-	db, err = gorm.Open(setting.DatabaseSetting.Type, fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8&parseTime=True&loc=Local",
-		setting.DatabaseSetting.User,
-		setting.DatabaseSetting.Password,
-		setting.DatabaseSetting.Host,
-		setting.DatabaseSetting.Name))
+// DB is an open database, the queries of the blog are its methods
+type DB struct {
+	conn *gorm.DB
+}
+
+// Open connects to the database configured in s
+func Open(s *setting.Database) (*DB, error) {
+	conn, err := open(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DB{conn: conn}, nil
+}
+
+// open connects to the backend selected by the [database] Type setting
+// and installs the callbacks shared by every backend
+func open(s *setting.Database) (*gorm.DB, error) {
+	d, err := getDialect(s.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	dsn := d.DSN(s)
+	if d.Name == DIALECT_SQLITE && dsn != SQLITE_MEMORY {
+		dir := filepath.Dir(strings.TrimPrefix(strings.SplitN(dsn, "?", 2)[0], "file:"))
+		if err := file.IsNotExistMkDir(dir); err != nil {
+			return nil, fmt.Errorf("file.IsNotExistMkDir src: %s, err: %v", dir, err)
+		}
+	}
 
+	conn, err := gorm.Open(d.Name, dsn)
 	if err != nil {
-		log.Fatalf("models.Setup err: %v", err)
+		return nil, err
 	}
 
 	gorm.DefaultTableNameHandler = func(db *gorm.DB, defaultTableName string) string {
-		return setting.DatabaseSetting.TablePrefix + defaultTableName
+		return s.TablePrefix + defaultTableName
+	}
+
+	conn.SingularTable(true)
+	conn.InstantSet(tablePrefixKey, s.TablePrefix)
+	conn.Callback().Create().Replace("gorm:update_time_stamp", updateTimeStampForCreateCallback)
+	conn.Callback().Update().Replace("gorm:update_time_stamp", updateTimeStampForUpdateCallback)
+	conn.Callback().Delete().Replace("gorm:delete", deleteCallback)
+	registerMetricsCallbacks(conn)
+	conn.DB().SetMaxIdleConns(10)
+	conn.DB().SetMaxOpenConns(100)
+	if d.MaxOpenConns != nil {
+		if n := d.MaxOpenConns(s); n > 0 {
+			conn.DB().SetMaxIdleConns(n)
+			conn.DB().SetMaxOpenConns(n)
+		}
+	}
+
+	// an in-memory database starts empty on every run, bring it up to date right away.
+	// Persistent databases are migrated explicitly with the `migrate up` subcommand
+	if dsn == SQLITE_MEMORY {
+		if err := migrateUp(conn); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// migrateUp applies every pending migration
+func migrateUp(conn *gorm.DB) error {
+	m, err := migrate.NewMigrator(conn, migrations)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.Up()
+	return err
+}
+
+// Transaction runs fn in a database transaction, it is rolled back when fn returns an error
+func (d *DB) Transaction(fn func(tx *gorm.DB) error) (err error) {
+	tx := d.conn.Begin()
+	if err = tx.Error; err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
 	}
 
-	db.SingularTable(true)
-	db.Callback().Create().Replace("gorm:update_time_stamp", updateTimeStampForCreateCallback)
-	db.Callback().Update().Replace("gorm:update_time_stamp", updateTimeStampForUpdateCallback)
-	db.Callback().Delete().Replace("gorm:delete", deleteCallback)
-	db.DB().SetMaxIdleConns(10)
-	db.DB().SetMaxOpenConns(100)
+	return tx.Commit().Error
+}
+
+// Ping checks the database answers within ctx
+func (d *DB) Ping(ctx context.Context) error {
+	return d.conn.DB().PingContext(ctx)
+}
+
+// Dialect returns the name of the backend, DIALECT_MYSQL or DIALECT_SQLITE
+func (d *DB) Dialect() string {
+	return d.conn.Dialect().GetName()
 }
 
-// CloseDB closes database connection (unnecessary)
-func CloseDB() {
-	defer db.Close()
+// Close closes the database connections, it waits for the running queries
+func (d *DB) Close() error {
+	return d.conn.Close()
 }
 
 // updateTimeStampForCreateCallback will set `CreatedOn`, `ModifiedOn` when creating
@@ -14,9 +14,14 @@ type Tag struct {
 }
 
 // ExistTagByName checks if there is a tag with the same name
-func ExistTagByName(name string) (bool, error) {
+func (d *DB) ExistTagByName(name string) (bool, error) {
+	return ExistTagByNameTx(d.conn, name)
+}
+
+// ExistTagByNameTx checks if there is a tag with the same name inside a transaction
+func ExistTagByNameTx(tx *gorm.DB, name string) (bool, error) {
 	var tag Tag
-	err := db.Select("id").Where("name = ? AND deleted_on = ? ", name, 0).First(&tag).Error
+	err := tx.Select("id").Where("name = ? AND deleted_on = ? ", name, 0).First(&tag).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return false, err
 	}
@@ -29,30 +34,35 @@ func ExistTagByName(name string) (bool, error) {
 }
 
 // AddTag Add a Tag
-func AddTag(name string, state int, createdBy string) error {
+func (d *DB) AddTag(name string, state int, createdBy string) error {
+	return AddTagTx(d.conn, name, state, createdBy)
+}
+
+// AddTagTx Add a Tag inside a transaction
+func AddTagTx(tx *gorm.DB, name string, state int, createdBy string) error {
 	tag := Tag{
 		Name:      name,
 		State:     state,
 		CreatedBy: createdBy,
 	}
-	if err := db.Create(&tag).Error; err != nil {
+	if err := tx.Create(&tag).Error; err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// GetTags gets a list of tags based on paging and constraints
-func GetTags(pageNum int, pageSize int, maps interface{}) ([]Tag, error) {
+// GetTags gets a list of tags based on paging and constraints, a pageSize of 0 gets all of them
+func (d *DB) GetTags(pageNum int, pageSize int, maps interface{}) ([]Tag, error) {
 	var (
 		tags []Tag
 		err  error
 	)
 
-	if pageSize > 0 && pageNum > 0 {
-		err = db.Where(maps).Find(&tags).Offset(pageNum).Limit(pageSize).Error
+	if pageSize > 0 {
+		err = d.conn.Where(maps).Offset(pageNum).Limit(pageSize).Find(&tags).Error
 	} else {
-		err = db.Where(maps).Find(&tags).Error
+		err = d.conn.Where(maps).Find(&tags).Error
 	}
 
 	if err != nil && err != gorm.ErrRecordNotFound {
@@ -63,9 +73,9 @@ func GetTags(pageNum int, pageSize int, maps interface{}) ([]Tag, error) {
 }
 
 // GetTagTotal counts the total number of tags based on the constraint
-func GetTagTotal(maps interface{}) (int, error) {
+func (d *DB) GetTagTotal(maps interface{}) (int, error) {
 	var count int
-	if err := db.Model(&Tag{}).Where(maps).Count(&count).Error; err != nil {
+	if err := d.conn.Model(&Tag{}).Where(maps).Count(&count).Error; err != nil {
 		return 0, err
 	}
 
@@ -73,9 +83,14 @@ func GetTagTotal(maps interface{}) (int, error) {
 }
 
 // ExistTagByID determines whether a Tag exists based on the ID
-func ExistTagByID(id int) (bool, error) {
+func (d *DB) ExistTagByID(id int) (bool, error) {
+	return ExistTagByIDTx(d.conn, id)
+}
+
+// ExistTagByIDTx determines whether a Tag exists based on the ID inside a transaction
+func ExistTagByIDTx(tx *gorm.DB, id int) (bool, error) {
 	var tag Tag
-	err := db.Select("id").Where("id = ? AND deleted_on = ? ", id, 0).First(&tag).Error
+	err := tx.Select("id").Where("id = ? AND deleted_on = ? ", id, 0).First(&tag).Error
 	if err != nil && err != gorm.ErrRecordNotFound {
 		return false, err
 	}
@@ -87,8 +102,8 @@ func ExistTagByID(id int) (bool, error) {
 }
 
 // DeleteTag delete a tag
-func DeleteTag(id int) error {
-	if err := db.Where("id = ?", id).Delete(&Tag{}).Error; err != nil {
+func (d *DB) DeleteTag(id int) error {
+	if err := d.conn.Where("id = ?", id).Delete(&Tag{}).Error; err != nil {
 		return err
 	}
 
@@ -96,8 +111,8 @@ func DeleteTag(id int) error {
 }
 
 // EditTag modify a single tag
-func EditTag(id int, data interface{}) error {
-	if err := db.Model(&Tag{}).Where("id = ? AND deleted_on = ? ", id, 0).Updates(data).Error; err != nil {
+func (d *DB) EditTag(id int, data interface{}) error {
+	if err := d.conn.Model(&Tag{}).Where("id = ? AND deleted_on = ? ", id, 0).Updates(data).Error; err != nil {
 		return err
 	}
 
@@ -105,14 +120,24 @@ func EditTag(id int, data interface{}) error {
 }
 
 // CleanAllTag clear all tag
-func CleanAllTag() (bool, error) {
-	if err := db.Unscoped().Where("deleted_on != ? ", 0).Delete(&Tag{}).Error; err != nil {
+func (d *DB) CleanAllTag() (bool, error) {
+	if err := d.conn.Unscoped().Where("deleted_on != ? ", 0).Delete(&Tag{}).Error; err != nil {
 		return false, err
 	}
 
 	return true, nil
 }
 
+// CleanTagsDeletedBefore hard deletes the tags soft deleted before the unix time
+func (d *DB) CleanTagsDeletedBefore(before int64) (int64, error) {
+	result := d.conn.Unscoped().Where("deleted_on != ? AND deleted_on < ? ", 0, before).Delete(&Tag{})
+	if err := result.Error; err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected, nil
+}
+
 //type Tag struct {
 //	Model
 //
@@ -5,8 +5,12 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/EGGYC/go-gin-example/pkg/e"
+	"github.com/EGGYC/go-gin-example/pkg/logging"
 )
 
+// CODE_KEY is the gin context key of the e code sent by Response
+const CODE_KEY = "e_code"
+
 type Gin struct {
 	C *gin.Context
 }
@@ -17,8 +21,14 @@ type Response struct {
 	Data interface{} `json:"data"`
 }
 
+// Logger returns the request-scoped logger, it carries the request ID and the user
+func (g *Gin) Logger() *logging.Logger {
+	return logging.FromContext(g.C.Request.Context())
+}
+
 // Response setting gin.JSON
 func (g *Gin) Response(httpCode, errCode int, data interface{}) {
+	g.C.Set(CODE_KEY, errCode)
 	g.C.JSON(httpCode, Response{
 		Code: errCode,
 		Msg:  e.GetMsg(errCode),
@@ -26,3 +36,13 @@ func (g *Gin) Response(httpCode, errCode int, data interface{}) {
 	})
 	return
 }
+
+// Code returns the e code sent by Response, ok is false before any response
+func (g *Gin) Code() (code int, ok bool) {
+	value, ok := g.C.Get(CODE_KEY)
+	if !ok {
+		return 0, false
+	}
+
+	return value.(int), true
+}
@@ -0,0 +1,73 @@
+// Package cache 缓存接口及其实现：进程内 LRU、Redis，以及不缓存的 Nop
+// 使用哪种实现由 app.ini 的 [cache] Type 决定
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/EGGYC/go-gin-example/pkg/gredis"
+	"github.com/EGGYC/go-gin-example/pkg/logging"
+	"github.com/EGGYC/go-gin-example/pkg/setting"
+)
+
+// cache types of [cache] Type
+const (
+	TYPE_REDIS  = "redis"
+	TYPE_MEMORY = "memory"
+	TYPE_NONE   = "none"
+)
+
+// ErrMiss is returned by Get when the key isn't cached or has expired
+var ErrMiss = errors.New("cache: miss")
+
+// Cache stores raw values under string keys, every value expires after its ttl
+type Cache interface {
+	Get(key string) ([]byte, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(keys ...string) error
+	// DeletePrefix deletes every key starting with prefix
+	DeletePrefix(prefix string) error
+}
+
+// New returns the cache configured in [cache], a Redis cache uses pool. A Redis cache
+// that can't be reached degrades to Nop, so the server keeps running on the database alone
+func New(s *setting.Cache, pool *redis.Pool) (Cache, error) {
+	switch strings.ToLower(s.Type) {
+	case TYPE_REDIS:
+		if err := gredis.Ping(pool); err != nil {
+			logging.Warn("cache: redis is unreachable, caching is disabled:", err)
+			return Nop{}, nil
+		}
+		return NewRedis(pool), nil
+	case TYPE_MEMORY:
+		return NewMemory(s.MemoryCapacity), nil
+	case TYPE_NONE, "":
+		return Nop{}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported cache type: %q", s.Type)
+}
+
+// Nop caches nothing, every Get misses
+type Nop struct{}
+
+func (Nop) Get(key string) ([]byte, error) {
+	return nil, ErrMiss
+}
+
+func (Nop) Set(key string, value []byte, ttl time.Duration) error {
+	return nil
+}
+
+func (Nop) Delete(keys ...string) error {
+	return nil
+}
+
+func (Nop) DeletePrefix(prefix string) error {
+	return nil
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DEFAULT_MEMORY_CAPACITY is used when [cache] MemoryCapacity isn't set
+const DEFAULT_MEMORY_CAPACITY = 10000
+
+// Memory is an in-process LRU cache holding at most capacity entries.
+// It isn't shared between instances, so a write on one instance can't invalidate another
+type Memory struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type entry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+func NewMemory(capacity int) *Memory {
+	if capacity <= 0 {
+		capacity = DEFAULT_MEMORY_CAPACITY
+	}
+
+	return &Memory{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (m *Memory) Get(key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.items[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+
+	e := el.Value.(*entry)
+	if time.Now().After(e.expiresAt) {
+		m.remove(el)
+		return nil, ErrMiss
+	}
+
+	m.ll.MoveToFront(el)
+	return e.value, nil
+}
+
+func (m *Memory) Set(key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if el, ok := m.items[key]; ok {
+		e := el.Value.(*entry)
+		e.value = value
+		e.expiresAt = expiresAt
+		m.ll.MoveToFront(el)
+		return nil
+	}
+
+	m.items[key] = m.ll.PushFront(&entry{key: key, value: value, expiresAt: expiresAt})
+	for m.ll.Len() > m.capacity {
+		m.remove(m.ll.Back())
+	}
+
+	return nil
+}
+
+func (m *Memory) Delete(keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := m.items[key]; ok {
+			m.remove(el)
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) DeletePrefix(prefix string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, el := range m.items {
+		if strings.HasPrefix(key, prefix) {
+			m.remove(el)
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) remove(el *list.Element) {
+	m.ll.Remove(el)
+	delete(m.items, el.Value.(*entry).key)
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/EGGYC/go-gin-example/pkg/gredis"
+)
+
+// Redis caches in Redis, so every instance sees the same entries and invalidations
+type Redis struct {
+	pool *redis.Pool
+}
+
+func NewRedis(pool *redis.Pool) *Redis {
+	return &Redis{pool: pool}
+}
+
+func (r *Redis) Get(key string) ([]byte, error) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	value, err := redis.Bytes(conn.Do("GET", key))
+	if err == redis.ErrNil {
+		return nil, ErrMiss
+	}
+
+	return value, err
+}
+
+func (r *Redis) Set(key string, value []byte, ttl time.Duration) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	seconds := int(ttl / time.Second)
+	if seconds <= 0 {
+		seconds = 1
+	}
+
+	_, err := conn.Do("SET", key, value, "EX", seconds)
+	return err
+}
+
+func (r *Redis) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = key
+	}
+
+	_, err := conn.Do("UNLINK", args...)
+	return err
+}
+
+func (r *Redis) DeletePrefix(prefix string) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := gredis.ScanDelete(conn, prefix)
+	return err
+}
@@ -0,0 +1,118 @@
+// Package container 按配置创建数据库、Redis、缓存、服务等依赖，交给路由和定时任务使用；
+// pkg/app 只保留 HTTP 响应与参数校验的辅助函数
+package container
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/EGGYC/go-gin-example/models"
+	"github.com/EGGYC/go-gin-example/pkg/cache"
+	"github.com/EGGYC/go-gin-example/pkg/denylist"
+	"github.com/EGGYC/go-gin-example/pkg/gredis"
+	"github.com/EGGYC/go-gin-example/pkg/lifecycle"
+	"github.com/EGGYC/go-gin-example/pkg/logging"
+	"github.com/EGGYC/go-gin-example/pkg/metrics"
+	"github.com/EGGYC/go-gin-example/pkg/search"
+	"github.com/EGGYC/go-gin-example/pkg/setting"
+	"github.com/EGGYC/go-gin-example/pkg/util"
+	"github.com/EGGYC/go-gin-example/service/article_service"
+	"github.com/EGGYC/go-gin-example/service/auth_service"
+	"github.com/EGGYC/go-gin-example/service/cache_service"
+	"github.com/EGGYC/go-gin-example/service/tag_service"
+)
+
+// App holds everything the handlers and the jobs use, New builds each piece from the
+// settings and hands it to the ones depending on it. Some state is still process-wide
+// and shared by every App of the process, so tests building several must not run in
+// parallel: the reloadable settings behind Settings, the table prefix of
+// gorm.DefaultTableNameHandler, the logger of logging.Setup used by the package-level
+// logging functions, the Prometheus metrics and the shutdown flag of pkg/health
+type App struct {
+	// Config is the snapshot App was built from, read the reloadable settings
+	// from Settings instead
+	Config *setting.Snapshot
+	// Settings returns the latest snapshot, a handler loads it once and reads every
+	// reloadable setting of the request from it
+	Settings func() *setting.Snapshot
+
+	DB       *models.DB
+	Redis    *redis.Pool
+	Cache    cache.Cache
+	Logger   *logging.Logger
+	Tokens   *util.TokenSigner
+	Denylist *denylist.Denylist
+	Search   search.Index
+
+	Articles *article_service.Service
+	Tags     *tag_service.Service
+	Auth     *auth_service.Service
+}
+
+// Options change how New builds App
+type Options struct {
+	// Logger replaces the log files of the [app] section, such as a logger writing to
+	// the test output. logging.Setup isn't called then
+	Logger *logging.Logger
+}
+
+// New opens the database, the log files and the Redis pool of cfg in that order, and
+// registers how to close each one with lc as soon as it is open. On error the
+// resources already open are left for lc to close
+func New(cfg *setting.Snapshot, lc *lifecycle.Manager, opts *Options) (*App, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	a := &App{Config: cfg, Settings: setting.Current}
+
+	db, err := models.Open(&cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("models.Open: %v", err)
+	}
+	a.DB = db
+	lc.Add("database", func(ctx context.Context) error {
+		return db.Close()
+	})
+
+	a.Logger = opts.Logger
+	if a.Logger == nil {
+		if err := logging.Setup(&cfg.App); err != nil {
+			return nil, fmt.Errorf("logging.Setup: %v", err)
+		}
+		lc.Add("logging", func(ctx context.Context) error {
+			return logging.Close()
+		})
+		a.Logger = logging.Default()
+	}
+
+	a.Redis = gredis.NewPool(&cfg.Redis)
+	lc.Add("redis", func(ctx context.Context) error {
+		return a.Redis.Close()
+	})
+	metrics.SetRedisPool(a.Redis)
+	a.Denylist = denylist.New(a.Redis)
+
+	a.Cache, err = cache.New(&cfg.Cache, a.Redis)
+	if err != nil {
+		return nil, fmt.Errorf("cache.New: %v", err)
+	}
+
+	a.Tokens, err = util.NewTokenSigner(&cfg.App)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Search, err = search.New(&cfg.Search, a.DB)
+	if err != nil {
+		return nil, fmt.Errorf("search.New: %v", err)
+	}
+
+	cached := cache_service.New(a.Cache, &cfg.Cache)
+	a.Articles = article_service.New(&cfg.App, a.DB, cached, a.Search)
+	a.Tags = tag_service.New(&cfg.App, a.DB, cached, a.Articles)
+	a.Auth = auth_service.New(a.DB)
+
+	return a, nil
+}
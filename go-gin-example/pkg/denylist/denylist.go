@@ -0,0 +1,156 @@
+// Package denylist 记录已注销的 token ID（jti），直到 token 自然过期
+// 优先存储在 Redis 中以便多个实例共享，Redis 不可用时退回到进程内存
+package denylist
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/EGGYC/go-gin-example/pkg/gredis"
+	"github.com/EGGYC/go-gin-example/pkg/logging"
+)
+
+const keyPrefix = "TOKEN_DENYLIST_"
+
+// Denylist holds the revoked token IDs
+type Denylist struct {
+	// pool is nil when the token IDs are only kept in memory
+	pool   *redis.Pool
+	memory *memoryStore
+}
+
+// New uses Redis when pool answers, otherwise memory only
+func New(pool *redis.Pool) *Denylist {
+	d := &Denylist{memory: &memoryStore{items: make(map[string]int64)}}
+	if err := gredis.Ping(pool); err != nil {
+		logging.Warn("denylist falls back to memory, redis err:", err)
+		return d
+	}
+
+	d.pool = pool
+	return d
+}
+
+// UsesRedis reports whether the token IDs are shared through Redis, false once New
+// fell back to memory
+func (d *Denylist) UsesRedis() bool {
+	return d.pool != nil
+}
+
+// Revoke denies the token ID until expiresAt (unix time), when the token expires anyway
+func (d *Denylist) Revoke(jti string, expiresAt int64) error {
+	ttl := expiresAt - time.Now().Unix()
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+
+	d.memory.add(jti, expiresAt)
+	if d.pool != nil {
+		return gredis.Set(d.pool, keyPrefix+jti, expiresAt, int(ttl))
+	}
+
+	return nil
+}
+
+// Consume revokes the token ID like Revoke and reports whether this call did it, false
+// when it was already revoked or consumed. The check and the revocation are one step,
+// so of concurrent calls with the same ID only one gets true
+func (d *Denylist) Consume(jti string, expiresAt int64) (bool, error) {
+	ttl := expiresAt - time.Now().Unix()
+	if jti == "" || ttl <= 0 {
+		return false, nil
+	}
+
+	if d.pool == nil {
+		return d.memory.addIfAbsent(jti, expiresAt), nil
+	}
+	if d.memory.contains(jti) {
+		return false, nil
+	}
+
+	ok, err := gredis.SetNX(d.pool, keyPrefix+jti, expiresAt, int(ttl))
+	if err != nil || !ok {
+		return false, err
+	}
+	d.memory.add(jti, expiresAt)
+
+	return true, nil
+}
+
+// IsRevoked reports whether the token ID has been revoked
+func (d *Denylist) IsRevoked(jti string) (bool, error) {
+	if d.memory.contains(jti) {
+		return true, nil
+	}
+	if d.pool == nil {
+		return false, nil
+	}
+
+	_, err := gredis.Get(d.pool, keyPrefix+jti)
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// memoryStore keeps revoked token IDs with their expiry, expired ones are swept on add
+type memoryStore struct {
+	mu        sync.Mutex
+	items     map[string]int64
+	lastSweep int64
+}
+
+func (m *memoryStore) add(jti string, expiresAt int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep()
+	m.items[jti] = expiresAt
+}
+
+// addIfAbsent adds jti unless it is already there, it reports whether it added it
+func (m *memoryStore) addIfAbsent(jti string, expiresAt int64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.containsLocked(jti) {
+		return false
+	}
+	m.sweep()
+	m.items[jti] = expiresAt
+
+	return true
+}
+
+func (m *memoryStore) contains(jti string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.containsLocked(jti)
+}
+
+func (m *memoryStore) containsLocked(jti string) bool {
+	exp, ok := m.items[jti]
+	return ok && exp > time.Now().Unix()
+}
+
+// sweep drops the expired token IDs at most once a minute, m.mu must be held
+func (m *memoryStore) sweep() {
+	now := time.Now().Unix()
+	if now-m.lastSweep <= 60 {
+		return
+	}
+
+	for k, exp := range m.items {
+		if exp <= now {
+			delete(m.items, k)
+		}
+	}
+	m.lastSweep = now
+}
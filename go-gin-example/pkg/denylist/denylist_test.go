@@ -0,0 +1,66 @@
+package denylist
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConsume(t *testing.T) {
+	d := New(nil)
+	exp := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		jti       string
+		expiresAt int64
+		want      bool
+	}{
+		{"a", exp, true},
+		// a token is consumed once
+		{"a", exp, false},
+		{"b", exp, true},
+		{"", exp, false},
+		{"expired", time.Now().Unix() - 1, false},
+	}
+	for _, tt := range tests {
+		got, err := d.Consume(tt.jti, tt.expiresAt)
+		if err != nil || got != tt.want {
+			t.Errorf("Consume(%q) = %v, %v, want %v", tt.jti, got, err, tt.want)
+		}
+	}
+
+	d.Revoke("revoked", exp)
+	if ok, _ := d.Consume("revoked", exp); ok {
+		t.Error("Consume of a revoked token ID = true, want false")
+	}
+	if revoked, _ := d.IsRevoked("b"); !revoked {
+		t.Error("IsRevoked of a consumed token ID = false, want true")
+	}
+}
+
+func TestConsumeConcurrently(t *testing.T) {
+	d := New(nil)
+	exp := time.Now().Add(time.Hour).Unix()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		consumed int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ok, _ := d.Consume("jti", exp); ok {
+				mu.Lock()
+				consumed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if consumed != 1 {
+		t.Fatalf("%d concurrent Consume calls succeeded, want 1", consumed)
+	}
+}
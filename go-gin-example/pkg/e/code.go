@@ -26,11 +26,25 @@ const (
 	ERROR_GET_ARTICLES_FAIL        = 10017
 	ERROR_GET_ARTICLE_FAIL         = 10018
 	ERROR_GEN_ARTICLE_POSTER_FAIL  = 10019
+	ERROR_EXPORT_ARTICLE_FAIL      = 10020
+	ERROR_IMPORT_ARTICLE_FAIL      = 10021
+	ERROR_SEARCH_ARTICLES_FAIL     = 10022
 
 	ERROR_AUTH_CHECK_TOKEN_FAIL    = 20001
 	ERROR_AUTH_CHECK_TOKEN_TIMEOUT = 20002
 	ERROR_AUTH_TOKEN               = 20003
 	ERROR_AUTH                     = 20004
+	ERROR_EXIST_AUTH               = 20005
+	ERROR_EXIST_AUTH_FAIL          = 20006
+	ERROR_ADD_AUTH_FAIL            = 20007
+	ERROR_AUTH_PASSWORD            = 20008
+	ERROR_EDIT_AUTH_PASSWORD_FAIL  = 20009
+	ERROR_AUTH_TOKEN_REVOKED       = 20010
+	ERROR_AUTH_LOGOUT_FAIL         = 20011
+	ERROR_AUTH_PERMISSION          = 20012
+	ERROR_NOT_EXIST_AUTH           = 20013
+	ERROR_EDIT_AUTH_ROLE_FAIL      = 20014
+	ERROR_LAST_ADMIN               = 20015
 
 	ERROR_UPLOAD_SAVE_IMAGE_FAIL    = 30001
 	ERROR_UPLOAD_CHECK_IMAGE_FAIL   = 30002
@@ -24,10 +24,24 @@ var MsgFlags = map[int]string{
 	ERROR_GET_ARTICLES_FAIL:         "获取多个文章失败",
 	ERROR_GET_ARTICLE_FAIL:          "获取单个文章失败",
 	ERROR_GEN_ARTICLE_POSTER_FAIL:   "生成文章海报失败",
+	ERROR_EXPORT_ARTICLE_FAIL:       "导出文章失败",
+	ERROR_IMPORT_ARTICLE_FAIL:       "导入文章失败",
+	ERROR_SEARCH_ARTICLES_FAIL:      "搜索文章失败",
 	ERROR_AUTH_CHECK_TOKEN_FAIL:     "Token鉴权失败",
 	ERROR_AUTH_CHECK_TOKEN_TIMEOUT:  "Token已超时",
 	ERROR_AUTH_TOKEN:                "Token生成失败",
 	ERROR_AUTH:                      "Token错误",
+	ERROR_EXIST_AUTH:                "已存在该用户名",
+	ERROR_EXIST_AUTH_FAIL:           "检查用户名是否存在失败",
+	ERROR_ADD_AUTH_FAIL:             "注册用户失败",
+	ERROR_AUTH_PASSWORD:             "原密码错误",
+	ERROR_EDIT_AUTH_PASSWORD_FAIL:   "修改密码失败",
+	ERROR_AUTH_TOKEN_REVOKED:        "Token已注销",
+	ERROR_AUTH_LOGOUT_FAIL:          "注销失败",
+	ERROR_AUTH_PERMISSION:           "没有操作权限",
+	ERROR_NOT_EXIST_AUTH:            "该用户不存在",
+	ERROR_EDIT_AUTH_ROLE_FAIL:       "修改用户角色失败",
+	ERROR_LAST_ADMIN:                "不能取消最后一个管理员的角色",
 	ERROR_UPLOAD_SAVE_IMAGE_FAIL:    "保存图片失败",
 	ERROR_UPLOAD_CHECK_IMAGE_FAIL:   "检查图片失败",
 	ERROR_UPLOAD_CHECK_IMAGE_FORMAT: "校验图片错误，图片格式或大小有问题",
@@ -5,16 +5,16 @@ import "github.com/EGGYC/go-gin-example/pkg/setting"
 const EXT = ".xlsx"
 
 // GetExcelFullUrl get the full access path of the Excel file
-func GetExcelFullUrl(name string) string {
-	return setting.AppSetting.PrefixUrl + "/" + GetExcelPath() + name
+func GetExcelFullUrl(app *setting.App, name string) string {
+	return app.PrefixUrl + "/" + GetExcelPath(app) + name
 }
 
 // GetExcelPath get the relative save path of the Excel file
-func GetExcelPath() string {
-	return setting.AppSetting.ExportSavePath
+func GetExcelPath(app *setting.App) string {
+	return app.ExportSavePath
 }
 
 // GetExcelFullPath Get the full save path of the Excel file
-func GetExcelFullPath() string {
-	return setting.AppSetting.RuntimeRootPath + GetExcelPath()
+func GetExcelFullPath(app *setting.App) string {
+	return app.RuntimeRootPath + GetExcelPath(app)
 }
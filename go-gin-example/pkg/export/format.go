@@ -0,0 +1,37 @@
+package export
+
+import "strings"
+
+// export formats and the extensions of their files
+const (
+	FORMAT_XLSX  = "xlsx"
+	FORMAT_CSV   = "csv"
+	FORMAT_JSONL = "jsonl"
+
+	EXT_CSV   = ".csv"
+	EXT_JSONL = ".jsonl"
+)
+
+var formatExts = map[string]string{
+	FORMAT_XLSX:  EXT,
+	FORMAT_CSV:   EXT_CSV,
+	FORMAT_JSONL: EXT_JSONL,
+}
+
+// GetFormatExt get the file extension of a format, ok is false for unknown formats
+func GetFormatExt(format string) (string, bool) {
+	ext, ok := formatExts[format]
+	return ext, ok
+}
+
+// GetFormatByName get the format of a file from its extension
+func GetFormatByName(name string) (string, bool) {
+	name = strings.ToLower(name)
+	for format, ext := range formatExts {
+		if strings.HasSuffix(name, ext) {
+			return format, true
+		}
+	}
+
+	return "", false
+}
@@ -7,6 +7,7 @@ import (
 	"mime/multipart"
 	"os"
 	"path"
+	"path/filepath"
 )
 
 func GetSize(f multipart.File) (int, error) {
@@ -50,6 +51,19 @@ func MkDir(src string) error {
 	return nil
 }
 
+// CheckWritable creates and removes a temporary file in dir
+func CheckWritable(dir string) error {
+	f, err := ioutil.TempFile(dir, ".writable-")
+	if err != nil {
+		return err
+	}
+
+	name := f.Name()
+	f.Close()
+
+	return os.Remove(name)
+}
+
 func Open(name string, flag int, perm os.FileMode) (*os.File, error) {
 	f, err := os.OpenFile(name, flag, perm)
 	if err != nil {
@@ -59,20 +73,24 @@ func Open(name string, flag int, perm os.FileMode) (*os.File, error) {
 	return f, nil
 }
 
-// MustOpen maximize trying to open the file
+// MustOpen maximize trying to open the file, a relative filePath is relative to the
+// working directory
 func MustOpen(fileName, filePath string) (*os.File, error) {
-	dir, err := os.Getwd()
-	if err != nil {
-		return nil, fmt.Errorf("os.Getwd err: %v", err)
+	src := filePath
+	if !filepath.IsAbs(filePath) {
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("os.Getwd err: %v", err)
+		}
+		src = dir + "/" + filePath
 	}
 
-	src := dir + "/" + filePath
 	perm := CheckPermission(src)
 	if perm == true {
 		return nil, fmt.Errorf("file.CheckPermission Permission denied src: %s", src)
 	}
 
-	err = IsNotExistMkDir(src)
+	err := IsNotExistMkDir(src)
 	if err != nil {
 		return nil, fmt.Errorf("file.IsNotExistMkDir src: %s, err: %v", src, err)
 	}
@@ -1,7 +1,10 @@
 package gredis
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"strings"
 	"time"
 
 	"github.com/gomodule/redigo/redis"
@@ -9,20 +12,24 @@ import (
 	"github.com/EGGYC/go-gin-example/pkg/setting"
 )
 
-var RedisConn *redis.Pool
-
-func Setup() error {
-	RedisConn = &redis.Pool{ // 设置 RedisConn 为 redis.Pool（连接池）并配置了它的一些参数：
-		MaxIdle:     setting.RedisSetting.MaxIdle,     // MaxIdle：最大空闲连接数
-		MaxActive:   setting.RedisSetting.MaxActive,   // MaxActive：在给定时间内，允许分配的最大连接数（当为零时，没有限制）
-		IdleTimeout: setting.RedisSetting.IdleTimeout, // IdleTimeout：在给定时间内将会保持空闲状态，若到达时间限制则关闭连接（当为零时，没有限制）
+// CONNECT_TIMEOUT bounds dialing Redis and the PING checking an idle connection before
+// it is reused, so a server that stopped answering doesn't hang the callers
+const CONNECT_TIMEOUT = 5 * time.Second
+
+// NewPool returns a connection pool for the [redis] section s, connections are
+// dialed on first use so Redis doesn't have to be up yet. Close the pool when done
+func NewPool(s *setting.Redis) *redis.Pool {
+	return &redis.Pool{ // 返回 redis.Pool（连接池）并配置了它的一些参数：
+		MaxIdle:     s.MaxIdle,     // MaxIdle：最大空闲连接数
+		MaxActive:   s.MaxActive,   // MaxActive：在给定时间内，允许分配的最大连接数（当为零时，没有限制）
+		IdleTimeout: s.IdleTimeout, // IdleTimeout：在给定时间内将会保持空闲状态，若到达时间限制则关闭连接（当为零时，没有限制）
 		Dial: func() (redis.Conn, error) { // Dial：提供创建和配置应用程序连接的一个函数
-			c, err := redis.Dial("tcp", setting.RedisSetting.Host)
+			c, err := redis.Dial("tcp", s.Host, redis.DialConnectTimeout(CONNECT_TIMEOUT))
 			if err != nil {
 				return nil, err
 			}
-			if setting.RedisSetting.Password != "" {
-				if _, err := c.Do("AUTH", setting.RedisSetting.Password); err != nil {
+			if s.Password != "" {
+				if _, err := c.Do("AUTH", s.Password); err != nil {
 					c.Close()
 					return nil, err
 				}
@@ -30,16 +37,46 @@ func Setup() error {
 			return c, err
 		},
 		TestOnBorrow: func(c redis.Conn, t time.Time) error { // TestOnBorrow：可选的应用程序检查健康功能
-			_, err := c.Do("PING")
+			_, err := redis.DoWithTimeout(c, CONNECT_TIMEOUT, "PING")
 			return err
 		},
 	}
+}
 
-	return nil
+// Ping checks Redis answers, it fails when there is no pool
+func Ping(pool *redis.Pool) error {
+	return PingContext(context.Background(), pool)
 }
 
-func Set(key string, data interface{}, time int) error {
-	conn := RedisConn.Get()
+// PingContext checks Redis answers before the deadline of ctx. Waiting for a connection
+// of the pool stops when ctx is done, the reply is read with the time left
+func PingContext(ctx context.Context, pool *redis.Pool) error {
+	if pool == nil {
+		return errors.New("gredis: no pool")
+	}
+
+	conn, err := pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		_, err = conn.Do("PING")
+		return err
+	}
+	timeout := time.Until(deadline)
+	if timeout <= 0 {
+		return context.DeadlineExceeded
+	}
+
+	_, err = redis.DoWithTimeout(conn, timeout, "PING")
+	return err
+}
+
+func Set(pool *redis.Pool, key string, data interface{}, time int) error {
+	conn := pool.Get()
 	defer conn.Close()
 
 	value, err := json.Marshal(data)
@@ -60,8 +97,30 @@ func Set(key string, data interface{}, time int) error {
 	return nil
 }
 
-func Exists(key string) bool {
-	conn := RedisConn.Get()
+// SetNX sets key for time seconds unless it exists, in one command so only one of
+// concurrent callers gets true
+func SetNX(pool *redis.Pool, key string, data interface{}, time int) (bool, error) {
+	conn := pool.Get()
+	defer conn.Close()
+
+	value, err := json.Marshal(data)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = redis.String(conn.Do("SET", key, value, "EX", time, "NX"))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func Exists(pool *redis.Pool, key string) bool {
+	conn := pool.Get()
 	defer conn.Close()
 
 	exists, err := redis.Bool(conn.Do("EXISTS", key)) // 将命令返回转为布尔值
@@ -73,8 +132,8 @@ func Exists(key string) bool {
 }
 
 // Get 在连接池中获取一个活跃连接
-func Get(key string) ([]byte, error) {
-	conn := RedisConn.Get()
+func Get(pool *redis.Pool, key string) ([]byte, error) {
+	conn := pool.Get()
 	defer conn.Close()
 
 	reply, err := redis.Bytes(conn.Do("GET", key)) // 将命令返回转为 Bytes
@@ -85,28 +144,74 @@ func Get(key string) ([]byte, error) {
 	return reply, nil
 }
 
-func Delete(key string) (bool, error) {
-	conn := RedisConn.Get()
+func Delete(pool *redis.Pool, key string) (bool, error) {
+	conn := pool.Get()
 	defer conn.Close()
 
 	return redis.Bool(conn.Do("DEL", key))
 }
 
-func LikeDeletes(key string) error {
-	conn := RedisConn.Get()
+// scanCount is the COUNT hint of every SCAN call, it bounds the work Redis does per call
+const scanCount = 1000
+
+// DeletePrefix deletes every key starting with prefix and returns how many were deleted
+func DeletePrefix(pool *redis.Pool, prefix string) (int, error) {
+	conn := pool.Get()
 	defer conn.Close()
 
-	keys, err := redis.Strings(conn.Do("KEYS", "*"+key+"*")) // 将命令返回转为 []string
-	if err != nil {
-		return err
-	}
+	return ScanDelete(conn, prefix)
+}
 
-	for _, key := range keys {
-		_, err = Delete(key)
+// ScanDelete deletes every key starting with prefix on conn. It walks the keyspace with
+// SCAN instead of KEYS so Redis is never blocked, and frees each batch with a pipelined
+// UNLINK (Redis 4.0+) in the background of the server
+func ScanDelete(conn redis.Conn, prefix string) (int, error) {
+	pattern := escapePattern(prefix) + "*"
+	cursor := 0
+	deleted := 0
+	for {
+		values, err := redis.Values(conn.Do("SCAN", cursor, "MATCH", pattern, "COUNT", scanCount))
 		if err != nil {
-			return err
+			return deleted, err
+		}
+
+		var keys []string
+		if _, err := redis.Scan(values, &cursor, &keys); err != nil {
+			return deleted, err
+		}
+
+		for _, key := range keys {
+			if err := conn.Send("UNLINK", key); err != nil {
+				return deleted, err
+			}
+		}
+		if err := conn.Flush(); err != nil {
+			return deleted, err
+		}
+		for range keys {
+			n, err := redis.Int(conn.Receive())
+			if err != nil {
+				return deleted, err
+			}
+			deleted += n
+		}
+
+		if cursor == 0 {
+			return deleted, nil
 		}
 	}
+}
 
-	return nil
+// escapePattern escapes the glob characters of a MATCH pattern so prefix matches literally
+func escapePattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
 }
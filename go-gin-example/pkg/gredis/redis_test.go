@@ -0,0 +1,212 @@
+package gredis
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+
+	"github.com/EGGYC/go-gin-example/pkg/setting"
+)
+
+// These need a Redis server, REDIS_ADDR defaults to 127.0.0.1:6379:
+//
+//	go test -run . -bench . ./pkg/gredis
+//
+// The benchmarks compare DeletePrefix with the KEYS based LikeDeletes it replaced on a
+// keyspace of benchKeys unrelated keys. Besides ns/op they report max-get-us, the worst
+// latency another client saw for a GET while the invalidation ran.
+const (
+	benchKeys     = 100000
+	benchListKeys = 100
+	testPrefix    = "GREDIS_TEST_"
+)
+
+var pool *redis.Pool
+
+func setupRedis(tb testing.TB) {
+	s := &setting.Redis{Host: os.Getenv("REDIS_ADDR"), MaxIdle: 10}
+	if s.Host == "" {
+		s.Host = "127.0.0.1:6379"
+	}
+	pool = NewPool(s)
+
+	if err := Ping(pool); err != nil {
+		pool.Close()
+		tb.Skipf("redis %s is not reachable: %v", s.Host, err)
+	}
+
+	tb.Cleanup(func() {
+		DeletePrefix(pool, testPrefix)
+		pool.Close()
+	})
+}
+
+// fill sets n keys named prefix0..prefixN in a pipeline
+func fill(tb testing.TB, prefix string, n int) {
+	conn := pool.Get()
+	defer conn.Close()
+
+	for i := 0; i < n; i++ {
+		conn.Send("SET", fmt.Sprintf("%s%d", prefix, i), "1")
+	}
+	if err := conn.Flush(); err != nil {
+		tb.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if _, err := conn.Receive(); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
+
+func TestDeletePrefix(t *testing.T) {
+	setupRedis(t)
+	fill(t, testPrefix+"LIST_", 2500)
+	fill(t, testPrefix+"KEEP_LIST_", 10)
+	fill(t, testPrefix+"LIS*", 10)
+
+	deleted, err := DeletePrefix(pool, testPrefix+"LIST_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 2500 {
+		t.Errorf("deleted %d keys, want 2500", deleted)
+	}
+
+	// keys that only contain the prefix, or match it as a glob, are kept
+	for _, key := range []string{testPrefix + "KEEP_LIST_0", testPrefix + "LIS*0"} {
+		if !Exists(pool, key) {
+			t.Errorf("%s was deleted", key)
+		}
+	}
+}
+
+func BenchmarkDeletePrefix(b *testing.B) {
+	benchmarkInvalidate(b, func(prefix string) error {
+		_, err := DeletePrefix(pool, prefix)
+		return err
+	})
+}
+
+func BenchmarkLikeDeletes(b *testing.B) {
+	benchmarkInvalidate(b, likeDeletes)
+}
+
+func benchmarkInvalidate(b *testing.B, invalidate func(prefix string) error) {
+	setupRedis(b)
+	fill(b, testPrefix+"ARTICLE_", benchKeys)
+
+	var maxGet time.Duration
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		fill(b, testPrefix+"LIST_", benchListKeys)
+		b.StartTimer()
+
+		worst, err := whileMeasuringGet(func() error {
+			return invalidate(testPrefix + "LIST_")
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if worst > maxGet {
+			maxGet = worst
+		}
+	}
+
+	b.ReportMetric(float64(maxGet.Microseconds()), "max-get-us")
+}
+
+// whileMeasuringGet runs fn while another connection keeps sending GET,
+// it returns the slowest GET
+func whileMeasuringGet(fn func() error) (time.Duration, error) {
+	var (
+		wg    sync.WaitGroup
+		worst time.Duration
+		done  = make(chan struct{})
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		conn := pool.Get()
+		defer conn.Close()
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			start := time.Now()
+			conn.Do("GET", testPrefix+"ARTICLE_0")
+			if d := time.Since(start); d > worst {
+				worst = d
+			}
+		}
+	}()
+
+	err := fn()
+	close(done)
+	wg.Wait()
+
+	return worst, err
+}
+
+// likeDeletes is the KEYS based implementation DeletePrefix replaced
+func likeDeletes(key string) error {
+	conn := pool.Get()
+	defer conn.Close()
+
+	keys, err := redis.Strings(conn.Do("KEYS", "*"+key+"*"))
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		_, err = Delete(pool, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// TestPingContextTimeout needs no Redis, the server accepts the connection and never replies
+func TestPingContextTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	silent := NewPool(&setting.Redis{Host: l.Addr().String(), MaxIdle: 1})
+	defer silent.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := PingContext(ctx, silent); err == nil {
+		t.Fatal("got no error from a server that doesn't reply")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("PingContext returned after %v, want about the 100ms of ctx", elapsed)
+	}
+}
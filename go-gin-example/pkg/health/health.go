@@ -0,0 +1,93 @@
+// Package health 就绪检查，关闭开始后立即变为未就绪，让负载均衡先摘掉流量
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CHECK_TIMEOUT bounds each check, a hung dependency fails the probe instead of blocking it
+const CHECK_TIMEOUT = 2 * time.Second
+
+var ErrShuttingDown = errors.New("shutting down")
+
+var shuttingDown int32
+
+// Check is one dependency of readiness. An Optional check that fails is reported as
+// degraded but keeps the instance ready, the server has a fallback for it
+type Check struct {
+	Name     string
+	Fn       func(ctx context.Context) error
+	Optional bool
+}
+
+// Result is the outcome of a Check, Error is empty when it passed
+type Result struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Degraded bool   `json:"degraded,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// SetShuttingDown makes every later Ready fail, it can't be undone
+func SetShuttingDown() {
+	atomic.StoreInt32(&shuttingDown, 1)
+}
+
+func IsShuttingDown() bool {
+	return atomic.LoadInt32(&shuttingDown) == 1
+}
+
+// Ready runs the checks concurrently, each one within CHECK_TIMEOUT.
+// It is not ready once the shutdown started, whatever the checks say
+func Ready(ctx context.Context, checks []Check) (bool, []Result) {
+	if IsShuttingDown() {
+		return false, []Result{{Name: "shutdown", Error: ErrShuttingDown.Error()}}
+	}
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+
+			results[i] = Result{Name: check.Name, OK: true}
+			if err := run(ctx, check.Fn); err != nil {
+				results[i].OK = false
+				results[i].Degraded = check.Optional
+				results[i].Error = err.Error()
+			}
+		}(i, check)
+	}
+	wg.Wait()
+
+	ready := true
+	for _, result := range results {
+		ready = ready && (result.OK || result.Degraded)
+	}
+
+	return ready, results
+}
+
+// run returns when fn does or when the timeout expires, a check that ignores ctx
+// keeps running in the background then
+func run(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, CHECK_TIMEOUT)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
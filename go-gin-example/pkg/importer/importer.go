@@ -0,0 +1,51 @@
+// Package importer 导入结果报告，标签与文章的导入共用
+package importer
+
+import (
+	"errors"
+	"strings"
+)
+
+// results of a single row
+const (
+	RESULT_CREATED           = "created"
+	RESULT_UPDATED           = "updated"
+	RESULT_SKIPPED_DUPLICATE = "skipped-duplicate"
+	RESULT_INVALID           = "invalid"
+)
+
+// ErrDryRun rolls back the import transaction of a dry run
+var ErrDryRun = errors.New("dry run")
+
+// Row is the result of a row of the file, Row counts from 1 like the spreadsheet.
+// Tags fill Name and CreatedBy, articles ID and Title
+type Row struct {
+	Row       int    `json:"row"`
+	ID        int    `json:"id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Title     string `json:"title,omitempty"`
+	CreatedBy string `json:"created_by,omitempty"`
+	Result    string `json:"result"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Report counts the rows by result, an import only has some of the results
+type Report struct {
+	DryRun  bool   `json:"dry_run"`
+	Created int    `json:"created"`
+	Updated int    `json:"updated"`
+	Skipped int    `json:"skipped"`
+	Invalid int    `json:"invalid"`
+	Rows    []*Row `json:"rows"`
+}
+
+// IsEmptyRow reports whether every cell of row is blank, such rows are ignored
+func IsEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+
+	return true
+}
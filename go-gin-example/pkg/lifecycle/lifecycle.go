@@ -0,0 +1,99 @@
+// Package lifecycle 按启动顺序登记需要关闭的资源，收到 SIGINT 或 SIGTERM 后按相反顺序关闭
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/EGGYC/go-gin-example/pkg/logging"
+)
+
+// Signals stop the server, SIGTERM is what Docker and Kubernetes send
+var Signals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+
+// StopFunc releases a resource, it should give up when ctx is done
+type StopFunc func(ctx context.Context) error
+
+type hook struct {
+	name string
+	stop StopFunc
+}
+
+// Manager stops what was registered with Add in the reverse order. The zero value
+// doesn't listen to Signals, such as in tests
+type Manager struct {
+	mu    sync.Mutex
+	hooks []hook
+
+	signals chan os.Signal
+}
+
+func New() *Manager {
+	m := &Manager{signals: make(chan os.Signal, 1)}
+	signal.Notify(m.signals, Signals...)
+
+	return m
+}
+
+// Add registers stop right after its resource started, so it is stopped before
+// everything started earlier
+func (m *Manager) Add(name string, stop StopFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hooks = append(m.hooks, hook{name: name, stop: stop})
+}
+
+// Wait blocks until one of Signals arrives or errc delivers an error, such as the
+// server failing to listen. It returns that error, or nil for a signal
+func (m *Manager) Wait(errc <-chan error) error {
+	select {
+	case sig := <-m.signals:
+		logging.Info("received signal:", sig)
+		return nil
+	case err := <-errc:
+		return err
+	}
+}
+
+// Shutdown runs the stop functions in the reverse order of Add, all of them run
+// even when one fails or ctx is done. Each failure is logged on its own, the
+// returned error lists them all. A second signal exits right away
+func (m *Manager) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case sig := <-m.signals:
+			logging.Error("received signal:", sig, "during shutdown, exiting now")
+			os.Exit(1)
+		case <-done:
+		}
+	}()
+
+	m.mu.Lock()
+	hooks := append([]hook(nil), m.hooks...)
+	m.mu.Unlock()
+
+	var errs []string
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		if err := h.stop(ctx); err != nil {
+			logging.Error("stop", h.name, "err:", err)
+			errs = append(errs, fmt.Sprintf("%s: %v", h.name, err))
+			continue
+		}
+		logging.Info("stopped", h.name)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("shutdown: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/EGGYC/go-gin-example/pkg/setting"
+)
+
+// access log formats of [app] AccessLogFormat
+const (
+	FORMAT_COMBINED = "combined"
+)
+
+// access has its own files named AccessLogSaveName, they rotate like the application log
+var access = &output{w: os.Stdout, format: FORMAT_COMBINED}
+
+// AccessEntry is one served request
+type AccessEntry struct {
+	Time      time.Time
+	Method    string
+	Route     string
+	URI       string
+	Proto     string
+	Status    int
+	Latency   time.Duration
+	Bytes     int
+	ClientIP  string
+	UserID    int
+	RequestID string
+	Referer   string
+	UserAgent string
+}
+
+func setupAccess(s *setting.App) error {
+	w, err := newLogWriter(s, s.AccessLogSaveName, nil)
+	if err != nil {
+		return err
+	}
+
+	format := strings.ToLower(s.AccessLogFormat)
+	if format != FORMAT_JSON {
+		format = FORMAT_COMBINED
+	}
+
+	access.mu.Lock()
+	access.w = w
+	access.format = format
+	access.mu.Unlock()
+
+	return nil
+}
+
+// Access writes the entry to the access log
+func Access(entry *AccessEntry) {
+	access.mu.Lock()
+	defer access.mu.Unlock()
+
+	var line []byte
+	if access.format == FORMAT_JSON {
+		line = entry.encodeJSON()
+	} else {
+		line = entry.encodeCombined()
+	}
+
+	access.w.Write(line)
+}
+
+// encodeCombined writes the Combined Log Format followed by our own fields:
+//
+//	127.0.0.1 - 1 [17/Oct/2026:10:00:00 +0800] "GET /api/v1/tags HTTP/1.1" 200 97 "-" "curl/7.81.0" route="/api/v1/tags" latency=0.001532 request_id=5f0c...
+func (e *AccessEntry) encodeCombined() []byte {
+	user := "-"
+	if e.UserID != 0 {
+		user = fmt.Sprint(e.UserID)
+	}
+
+	line := fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %d %s %s route=%s latency=%.6f request_id=%s\n",
+		orDash(e.ClientIP),
+		user,
+		e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		escape(e.Method), escape(e.URI), escape(e.Proto),
+		e.Status,
+		e.Bytes,
+		quote(e.Referer),
+		quote(e.UserAgent),
+		quote(e.Route),
+		e.Latency.Seconds(),
+		orDash(e.RequestID),
+	)
+
+	return []byte(line)
+}
+
+func (e *AccessEntry) encodeJSON() []byte {
+	line, _ := json.Marshal(struct {
+		Time      string  `json:"time"`
+		Method    string  `json:"method"`
+		Route     string  `json:"route"`
+		URI       string  `json:"uri"`
+		Proto     string  `json:"proto"`
+		Status    int     `json:"status"`
+		LatencyMs float64 `json:"latency_ms"`
+		Bytes     int     `json:"bytes"`
+		ClientIP  string  `json:"client_ip"`
+		UserID    int     `json:"user_id,omitempty"`
+		RequestID string  `json:"request_id,omitempty"`
+		Referer   string  `json:"referer,omitempty"`
+		UserAgent string  `json:"user_agent,omitempty"`
+	}{
+		Time:      e.Time.Format(time.RFC3339),
+		Method:    e.Method,
+		Route:     e.Route,
+		URI:       e.URI,
+		Proto:     e.Proto,
+		Status:    e.Status,
+		LatencyMs: float64(e.Latency.Microseconds()) / 1000,
+		Bytes:     e.Bytes,
+		ClientIP:  e.ClientIP,
+		UserID:    e.UserID,
+		RequestID: e.RequestID,
+		Referer:   e.Referer,
+		UserAgent: e.UserAgent,
+	})
+
+	return append(line, '\n')
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+// quote writes s as a quoted field, the client controls it so quotes and control characters are escaped
+func quote(s string) string {
+	if s == "" {
+		return `"-"`
+	}
+
+	return strconv.Quote(s)
+}
+
+// escape is quote without the surrounding quotes, for the parts of the request line
+func escape(s string) string {
+	s = strconv.Quote(s)
+
+	return s[1 : len(s)-1]
+}
@@ -5,22 +5,24 @@ package logging
 import (
 	"fmt"
 	"os"
-	"time"
 
 	"github.com/EGGYC/go-gin-example/pkg/file"
 	"github.com/EGGYC/go-gin-example/pkg/setting"
 )
 
-func getLogFilePath() string {
-	return fmt.Sprintf("%s%s", setting.AppSetting.RuntimeRootPath, setting.AppSetting.LogSavePath)
+func getLogFilePath(s *setting.App) string {
+	return fmt.Sprintf("%s%s", s.RuntimeRootPath, s.LogSavePath)
 }
 
-func getLogFileName() string {
-	return fmt.Sprintf("%s%s.%s",
-		setting.AppSetting.LogSaveName,
-		time.Now().Format(setting.AppSetting.TimeFormat),
-		setting.AppSetting.LogFileExt,
-	)
+// newLogWriter returns a rotating writer of the files named name in LogSavePath
+func newLogWriter(s *setting.App, name string, onOpen func(f *os.File)) (*rotateWriter, error) {
+	return newRotateWriter(getLogFilePath(s), name, s.LogFileExt, s.TimeFormat,
+		rotateOptions{
+			MaxSize:    int64(s.LogMaxSize),
+			MaxAge:     s.LogMaxAge,
+			MaxBackups: s.LogMaxBackups,
+			Compress:   s.LogCompress,
+		}, onOpen)
 }
 
 func openLogFile(fileName, filePath string) (*os.File, error) {
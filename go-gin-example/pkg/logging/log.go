@@ -1,24 +1,42 @@
 package logging
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/EGGYC/go-gin-example/pkg/setting"
 )
 
 type Level int
 
+// log formats of [app] LogFormat
+const (
+	FORMAT_TEXT = "text"
+	FORMAT_JSON = "json"
+)
+
 var (
+	// F is the log file currently written, it changes when the file is rotated
 	F *os.File
 
 	DefaultPrefix      = ""
 	DefaultCallerDepth = 2
 
-	logger     *log.Logger
-	logPrefix  = ""
 	levelFlags = []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+
+	// out is shared by every Logger, a line is written with a single Write under its lock
+	out = &output{w: os.Stderr, level: INFO, format: FORMAT_TEXT}
+
+	std = &Logger{}
 )
 
 const (
@@ -29,73 +47,291 @@ const (
 	FATAL
 )
 
-// Setup initialize the log instance
-func Setup() {
-	var err error
-	filePath := getLogFilePath()
-	fileName := getLogFileName()
-	F, err = openLogFile(fileName, filePath)
+func (l Level) String() string {
+	if l < DEBUG || l > FATAL {
+		return fmt.Sprintf("LEVEL(%d)", int(l))
+	}
+
+	return levelFlags[l]
+}
+
+// ParseLevel reads a level name such as "info" or "warn"
+func ParseLevel(name string) (Level, error) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if name == "WARNING" {
+		name = "WARN"
+	}
+	for i, flag := range levelFlags {
+		if flag == name {
+			return Level(i), nil
+		}
+	}
+
+	return INFO, fmt.Errorf("unknown log level: %q", name)
+}
+
+type output struct {
+	mu     sync.Mutex
+	w      io.Writer
+	level  Level
+	format string
+}
+
+// Logger writes leveled entries with key/value fields, it is safe for concurrent use.
+// The zero value logs without fields to the output set up by Setup
+type Logger struct {
+	// out is nil for the loggers writing to the output of Setup
+	out    *output
+	fields []interface{}
+}
+
+// New returns a logger of its own that writes the entries of level and above to w,
+// Setup and Close don't change it
+func New(w io.Writer, level Level, format string) *Logger {
+	return &Logger{out: &output{w: w, level: level, format: format}}
+}
+
+// Default returns the logger of the package functions, it writes to the output of Setup
+func Default() *Logger {
+	return std
+}
+
+func init() {
+	// the level follows [app] LogLevel when the settings are reloaded
+	setting.OnReload(func(prev, next *setting.Snapshot) {
+		if prev == nil || prev.App.LogLevel == next.App.LogLevel {
+			return
+		}
+
+		level, err := ParseLevel(next.App.LogLevel)
+		if err != nil {
+			Warn("keeping the log level, reload:", err)
+			return
+		}
+
+		out.mu.Lock()
+		out.level = level
+		out.mu.Unlock()
+		Info("log level reloaded:", level)
+	})
+}
+
+// Setup sends the entries of the package functions and the access log to the files
+// configured in s
+func Setup(s *setting.App) error {
+	level, err := ParseLevel(s.LogLevel)
 	if err != nil {
-		log.Fatalln(err)
+		return err
+	}
+	format := strings.ToLower(s.LogFormat)
+	if format != FORMAT_JSON {
+		format = FORMAT_TEXT
+	}
+
+	w, err := newLogWriter(s, s.LogSaveName, func(f *os.File) { F = f })
+	if err != nil {
+		return err
+	}
+	if err := setupAccess(s); err != nil {
+		w.Close()
+		return err
+	}
+
+	out.mu.Lock()
+	out.w = w
+	out.level = level
+	out.format = format
+	out.mu.Unlock()
+
+	return nil
+}
+
+// Close closes the log files, entries logged afterwards go to stderr
+func Close() error {
+	var errs []string
+	for _, o := range []*output{out, access} {
+		o.mu.Lock()
+		w := o.w
+		o.w = os.Stderr
+		o.mu.Unlock()
+
+		if c, ok := w.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	F = nil
+
+	if len(errs) > 0 {
+		return fmt.Errorf("logging.Close: %s", strings.Join(errs, "; "))
 	}
-	logger = log.New(F, DefaultPrefix, log.LstdFlags)
+
+	return nil
 }
 
-// 原init函数
-//func init() {
-//	filePath := getLogFileFullPath()
-//	F = openLogFile(filePath)
-//
-//	logger = log.New(F, DefaultPrefix, log.LstdFlags) // 创建一个新的日志记录器。out定义要写入日志数据的IO句柄。prefix定义每个生成的日志行的开头。flag定义了日志记录属性
-//}
+// With returns a logger that adds the key/value pairs to every entry
+func With(kv ...interface{}) *Logger {
+	return std.With(kv...)
+}
 
 func Debug(v ...interface{}) {
-	setPrefix(DEBUG)
-	logger.Println(v)
+	std.write(DEBUG, v)
 }
 
 func Info(v ...interface{}) {
-	setPrefix(INFO)
-	logger.Println(v)
+	std.write(INFO, v)
 }
 
 func Warn(v ...interface{}) {
-	setPrefix(WARNING)
-	logger.Println(v)
+	std.write(WARNING, v)
 }
 
 func Error(v ...interface{}) {
-	setPrefix(ERROR)
-	logger.Println(v)
+	std.write(ERROR, v)
 }
 
 func Fatal(v ...interface{}) {
-	setPrefix(FATAL)
-	logger.Fatalln(v)
+	std.write(FATAL, v)
+	os.Exit(1)
 }
 
-func setPrefix(level Level) {
-	_, file, line, ok := runtime.Caller(DefaultCallerDepth)
-	if ok {
-		logPrefix = fmt.Sprintf("[%s][%s:%d]", levelFlags[level], filepath.Base(file), line)
+// With returns a copy of l that also adds the key/value pairs to every entry
+func (l *Logger) With(kv ...interface{}) *Logger {
+	if len(kv)%2 != 0 {
+		kv = append(kv, "(MISSING)")
+	}
+
+	fields := make([]interface{}, 0, len(l.fields)+len(kv))
+	fields = append(fields, l.fields...)
+	fields = append(fields, kv...)
+
+	return &Logger{out: l.out, fields: fields}
+}
+
+func (l *Logger) Debug(v ...interface{}) {
+	l.write(DEBUG, v)
+}
+
+func (l *Logger) Info(v ...interface{}) {
+	l.write(INFO, v)
+}
+
+func (l *Logger) Warn(v ...interface{}) {
+	l.write(WARNING, v)
+}
+
+func (l *Logger) Error(v ...interface{}) {
+	l.write(ERROR, v)
+}
+
+func (l *Logger) Fatal(v ...interface{}) {
+	l.write(FATAL, v)
+	os.Exit(1)
+}
+
+// write encodes an entry, every exported method calls it directly so the caller is
+// always DefaultCallerDepth frames up
+func (l *Logger) write(level Level, v []interface{}) {
+	o := l.output()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if level < o.level {
+		return
+	}
+
+	caller := ""
+	if _, file, line, ok := runtime.Caller(DefaultCallerDepth); ok {
+		caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	}
+
+	msg := strings.TrimSuffix(fmt.Sprintln(v...), "\n")
+	var line []byte
+	if o.format == FORMAT_JSON {
+		line = encodeJSON(time.Now(), level, caller, msg, l.fields)
 	} else {
-		logPrefix = fmt.Sprintf("[%s]", levelFlags[level])
-	}
-
-	logger.SetPrefix(logPrefix)
-}
-
-//log.New：创建一个新的日志记录器。out定义要写入日志数据的IO句柄。prefix定义每个生成的日志行的开头。flag定义了日志记录属性
-//func New(out io.Writer, prefix string, flag int) *Logger {
-//	return &Logger{out: out, prefix: prefix, flag: flag}
-//}
-//log.LstdFlags：日志记录的格式属性之一，其余的选项如下
-//const (
-//	Ldate         = 1 << iota     // the date in the local time zone: 2009/01/23
-//	Ltime                         // the time in the local time zone: 01:23:23
-//	Lmicroseconds                 // microsecond resolution: 01:23:23.123123.  assumes Ltime.
-//	Llongfile                     // full file name and line number: /a/b/c/d.go:23
-//	Lshortfile                    // final file name element and line number: d.go:23. overrides Llongfile
-//	LUTC                          // if Ldate or Ltime is set, use UTC rather than the local time zone
-//	LstdFlags     = Ldate | Ltime // initial values for the standard logger
-//)
+		line = encodeText(time.Now(), level, caller, msg, l.fields)
+	}
+
+	o.w.Write(line)
+}
+
+func (l *Logger) output() *output {
+	if l.out != nil {
+		return l.out
+	}
+
+	return out
+}
+
+// encodeText writes `2006/01/02 15:04:05 [INFO][file.go:12] msg key=value`
+func encodeText(t time.Time, level Level, caller, msg string, fields []interface{}) []byte {
+	var b bytes.Buffer
+	b.WriteString(DefaultPrefix)
+	b.WriteString(t.Format("2006/01/02 15:04:05"))
+	fmt.Fprintf(&b, " [%s]", level)
+	if caller != "" {
+		fmt.Fprintf(&b, "[%s]", caller)
+	}
+	b.WriteString(" ")
+	b.WriteString(msg)
+
+	for i := 0; i < len(fields); i += 2 {
+		value := fmt.Sprint(fields[i+1])
+		if strings.ContainsAny(value, " \t\n\"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(&b, " %v=%s", fields[i], value)
+	}
+	b.WriteString("\n")
+
+	return b.Bytes()
+}
+
+// encodeJSON writes one JSON object per line, fields that can't be encoded are written as strings
+func encodeJSON(t time.Time, level Level, caller, msg string, fields []interface{}) []byte {
+	entry := make(map[string]interface{}, 4+len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		value := fields[i+1]
+		switch v := value.(type) {
+		case error:
+			value = v.Error()
+		case fmt.Stringer:
+			value = v.String()
+		}
+		entry[fmt.Sprint(fields[i])] = value
+	}
+	entry["time"] = t.Format(time.RFC3339)
+	entry["level"] = level.String()
+	entry["caller"] = caller
+	entry["msg"] = msg
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		for key, value := range entry {
+			entry[key] = fmt.Sprint(value)
+		}
+		line, _ = json.Marshal(entry)
+	}
+
+	return append(line, '\n')
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries l, e.g. a logger with the request ID
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(*Logger); ok {
+			return l
+		}
+	}
+
+	return std
+}
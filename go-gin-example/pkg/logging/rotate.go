@@ -0,0 +1,334 @@
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const compressSuffix = ".gz"
+
+// rotateOptions are the [app] Log* retention settings
+type rotateOptions struct {
+	// MaxSize in bytes rotates the file within a day, 0 only rotates daily
+	MaxSize int64
+	// MaxAge in days removes older rotated files, 0 keeps them
+	MaxAge int
+	// MaxBackups is the number of rotated files kept, 0 keeps them all
+	MaxBackups int
+	// Compress gzips the rotated files
+	Compress bool
+}
+
+// rotateWriter writes to <dir>/<name><date>.<ext>, it switches to a new file when the
+// date formatted by timeFormat changes and when the file would grow past MaxSize.
+// A file rotated by size is renamed to <name><date>.<n>.<ext> first.
+//
+// It is not safe for concurrent use, output serializes the writes.
+type rotateWriter struct {
+	dir        string
+	name       string
+	ext        string
+	timeFormat string
+	opts       rotateOptions
+
+	file *os.File
+	date string
+	size int64
+
+	// onOpen is told about every file opened, it may be nil
+	onOpen func(f *os.File)
+
+	now  func() time.Time
+	mill chan struct{}
+	// closeMill closes mill once, Close may be called again after a failure
+	closeMill sync.Once
+}
+
+func newRotateWriter(dir, name, ext, timeFormat string, opts rotateOptions, onOpen func(f *os.File)) (*rotateWriter, error) {
+	w := &rotateWriter{
+		dir:        dir,
+		name:       name,
+		ext:        ext,
+		timeFormat: timeFormat,
+		opts:       opts,
+		onOpen:     onOpen,
+		now:        time.Now,
+		mill:       make(chan struct{}, 1),
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	go w.millRun()
+	// files left behind by a previous run are compressed and pruned at startup
+	w.millSignal()
+
+	return w, nil
+}
+
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	if w.now().Format(w.timeFormat) != w.date {
+		if err := w.rotate(false); err != nil {
+			return 0, err
+		}
+	} else if w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.opts.MaxSize {
+		if err := w.rotate(true); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+
+	return n, err
+}
+
+// Close closes the current file and stops compressing and pruning, the writer
+// can't be used afterwards. Closing it again does nothing
+func (w *rotateWriter) Close() error {
+	w.closeMill.Do(func() {
+		close(w.mill)
+	})
+
+	return w.closeFile()
+}
+
+func (w *rotateWriter) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+
+	return err
+}
+
+// open opens the file of the current date in append mode
+func (w *rotateWriter) open() error {
+	w.date = w.now().Format(w.timeFormat)
+	f, err := openLogFile(w.fileName(w.date), w.dir)
+	if err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	w.file = f
+	w.size = info.Size()
+	if w.onOpen != nil {
+		w.onOpen(f)
+	}
+
+	return nil
+}
+
+// rotate closes the current file and opens the next one, bySize moves the full file
+// aside so the file of the day keeps its name
+func (w *rotateWriter) rotate(bySize bool) error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+
+	if bySize {
+		current := filepath.Join(w.dir, w.fileName(w.date))
+		if err := os.Rename(current, w.backupName(w.date)); err != nil {
+			return fmt.Errorf("rotate %s: %v", current, err)
+		}
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.millSignal()
+
+	return nil
+}
+
+func (w *rotateWriter) fileName(date string) string {
+	return fmt.Sprintf("%s%s.%s", w.name, date, w.ext)
+}
+
+// backupName returns the first free <name><date>.<n>.<ext>
+func (w *rotateWriter) backupName(date string) string {
+	for n := 1; ; n++ {
+		name := filepath.Join(w.dir, fmt.Sprintf("%s%s.%d.%s", w.name, date, n, w.ext))
+		if !exists(name) && !exists(name+compressSuffix) {
+			return name
+		}
+	}
+}
+
+func (w *rotateWriter) millSignal() {
+	select {
+	case w.mill <- struct{}{}:
+	default:
+	}
+}
+
+// millRun compresses and prunes rotated files off the write path
+func (w *rotateWriter) millRun() {
+	for range w.mill {
+		if err := w.millRunOnce(); err != nil {
+			fmt.Fprintf(os.Stderr, "logging: %v\n", err)
+		}
+	}
+}
+
+type logInfo struct {
+	path string
+	os.FileInfo
+}
+
+func (w *rotateWriter) millRunOnce() error {
+	files, err := w.oldLogFiles()
+	if err != nil {
+		return err
+	}
+
+	var remove []logInfo
+	if w.opts.MaxBackups > 0 && len(files) > w.opts.MaxBackups {
+		remove = append(remove, files[w.opts.MaxBackups:]...)
+		files = files[:w.opts.MaxBackups]
+	}
+	if w.opts.MaxAge > 0 {
+		cutoff := w.now().AddDate(0, 0, -w.opts.MaxAge)
+		var keep []logInfo
+		for _, f := range files {
+			if f.ModTime().Before(cutoff) {
+				remove = append(remove, f)
+			} else {
+				keep = append(keep, f)
+			}
+		}
+		files = keep
+	}
+
+	var errs []string
+	for _, f := range remove {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+	if w.opts.Compress {
+		for _, f := range files {
+			if strings.HasSuffix(f.path, compressSuffix) {
+				continue
+			}
+			if err := compressFile(f.path, f.FileInfo); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("log retention: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// oldLogFiles lists the files of this writer except the open one, newest first
+func (w *rotateWriter) oldLogFiles() ([]logInfo, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	current := w.fileName(w.now().Format(w.timeFormat))
+	var files []logInfo
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Name() == current || !w.isLogFile(entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, logInfo{path: filepath.Join(w.dir, entry.Name()), FileInfo: info})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().After(files[j].ModTime())
+	})
+
+	return files, nil
+}
+
+// isLogFile matches <name><date>[.<n>].<ext>[.gz]
+func (w *rotateWriter) isLogFile(name string) bool {
+	if !strings.HasPrefix(name, w.name) {
+		return false
+	}
+
+	name = strings.TrimSuffix(name, compressSuffix)
+	if !strings.HasSuffix(name, "."+w.ext) {
+		return false
+	}
+
+	date := strings.TrimSuffix(strings.TrimPrefix(name, w.name), "."+w.ext)
+	if i := strings.LastIndexByte(date, '.'); i >= 0 {
+		if _, err := strconv.Atoi(date[i+1:]); err == nil {
+			date = date[:i]
+		}
+	}
+	_, err := time.Parse(w.timeFormat, date)
+
+	return err == nil
+}
+
+// compressFile replaces src with src.gz and keeps its modification time for the retention
+func compressFile(src string, info os.FileInfo) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	dst := src + compressSuffix
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(dst)
+		}
+	}()
+
+	gz := gzip.NewWriter(out)
+	if _, err = io.Copy(gz, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	if err = os.Chtimes(dst, info.ModTime(), info.ModTime()); err != nil {
+		return err
+	}
+
+	return os.Remove(src)
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
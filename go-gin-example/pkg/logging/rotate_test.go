@@ -0,0 +1,338 @@
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// clock is the now of a test writer, tests move it forward
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+// newTestWriter opens a writer in a temporary directory. The mill isn't started, the
+// tests call millRunOnce themselves
+func newTestWriter(t *testing.T, opts rotateOptions, c *clock) *rotateWriter {
+	t.Helper()
+
+	// openLogFile takes a directory relative to the working directory, ending with a slash
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := filepath.Rel(wd, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &rotateWriter{
+		dir:        dir + "/",
+		name:       "log",
+		ext:        "log",
+		timeFormat: "20060102",
+		opts:       opts,
+		now:        c.now,
+		mill:       make(chan struct{}, 1),
+	}
+	if err := w.open(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		w.Close()
+	})
+
+	return w
+}
+
+// files returns the content of every file in the directory of w by name, gzipped
+// files are decompressed
+func files(t *testing.T, w *rotateWriter) map[string]string {
+	t.Helper()
+
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := make(map[string]string)
+	for _, entry := range entries {
+		f, err := os.Open(filepath.Join(w.dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var r io.Reader = f
+		if filepath.Ext(entry.Name()) == compressSuffix {
+			gz, err := gzip.NewReader(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r = gz
+		}
+		data, err := io.ReadAll(r)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[entry.Name()] = string(data)
+	}
+
+	return contents
+}
+
+// names lists the files in the directory of w, sorted
+func names(t *testing.T, w *rotateWriter) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	return names
+}
+
+// create writes the files of names with the given age
+func create(t *testing.T, w *rotateWriter, now time.Time, ages map[string]time.Duration) {
+	t.Helper()
+
+	for name, age := range ages {
+		path := filepath.Join(w.dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func write(t *testing.T, w *rotateWriter, lines ...string) {
+	t.Helper()
+
+	for _, line := range lines {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+var day = time.Date(2026, 10, 16, 23, 58, 0, 0, time.Local)
+
+func TestRotateByDate(t *testing.T) {
+	c := &clock{t: day}
+	w := newTestWriter(t, rotateOptions{}, c)
+
+	write(t, w, "a\n")
+	c.t = c.t.Add(time.Minute)
+	write(t, w, "b\n")
+	c.t = c.t.Add(time.Minute)
+	write(t, w, "c\n")
+
+	want := map[string]string{
+		"log20261016.log": "a\nb\n",
+		"log20261017.log": "c\n",
+	}
+	if got := files(t, w); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestRotateBySize(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxSize int64
+		lines   []string
+		want    map[string]string
+	}{
+		{
+			name:    "no limit",
+			maxSize: 0,
+			lines:   []string{"12345\n", "12345\n", "12345\n"},
+			want:    map[string]string{"log20261016.log": "12345\n12345\n12345\n"},
+		},
+		{
+			name:    "numbered in order",
+			maxSize: 10,
+			lines:   []string{"12345\n", "12345\n", "12345\n"},
+			want: map[string]string{
+				"log20261016.1.log": "12345\n",
+				"log20261016.2.log": "12345\n",
+				"log20261016.log":   "12345\n",
+			},
+		},
+		{
+			name:    "fills up to the limit",
+			maxSize: 12,
+			lines:   []string{"12345\n", "12345\n", "12345\n"},
+			want: map[string]string{
+				"log20261016.1.log": "12345\n12345\n",
+				"log20261016.log":   "12345\n",
+			},
+		},
+		{
+			name:    "a line longer than the limit isn't split",
+			maxSize: 4,
+			lines:   []string{"123456789\n"},
+			want:    map[string]string{"log20261016.log": "123456789\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWriter(t, rotateOptions{MaxSize: tt.maxSize}, &clock{t: day})
+			write(t, w, tt.lines...)
+
+			if got := files(t, w); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackupName(t *testing.T) {
+	tests := []struct {
+		existing []string
+		want     string
+	}{
+		{nil, "log20261016.1.log"},
+		{[]string{"log20261016.1.log"}, "log20261016.2.log"},
+		// a compressed backup keeps its number
+		{[]string{"log20261016.1.log.gz", "log20261016.2.log"}, "log20261016.3.log"},
+		{[]string{"log20261015.1.log"}, "log20261016.1.log"},
+	}
+	for _, tt := range tests {
+		w := newTestWriter(t, rotateOptions{}, &clock{t: day})
+		for _, name := range tt.existing {
+			create(t, w, day, map[string]time.Duration{name: 0})
+		}
+
+		if got := filepath.Base(w.backupName("20261016")); got != tt.want {
+			t.Errorf("backupName with %v = %s, want %s", tt.existing, got, tt.want)
+		}
+	}
+}
+
+func TestIsLogFile(t *testing.T) {
+	w := &rotateWriter{name: "log", ext: "log", timeFormat: "20060102"}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"log20261016.log", true},
+		{"log20261016.3.log", true},
+		{"log20261016.log.gz", true},
+		{"log20261016.3.log.gz", true},
+		{"access20261016.log", false},
+		{"log20261016.txt", false},
+		{"log20261316.log", false},
+		{"log2026101.log", false},
+		{"log20261016.x.log", false},
+		{"log.log", false},
+	}
+	for _, tt := range tests {
+		if got := w.isLogFile(tt.name); got != tt.want {
+			t.Errorf("isLogFile(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMillRunOncePrunes(t *testing.T) {
+	hour := time.Hour
+	ages := map[string]time.Duration{
+		"log20261016.1.log":  2 * hour,
+		"log20261015.log":    30 * hour,
+		"log20261014.log.gz": 48 * hour,
+		"log20261010.log":    6 * 24 * hour,
+		// not files of the writer
+		"access20261001.log": 15 * 24 * hour,
+		"notes.txt":          15 * 24 * hour,
+	}
+	// the open file and the files of other writers are never removed
+	always := []string{"access20261001.log", "log20261016.log", "notes.txt"}
+
+	tests := []struct {
+		name string
+		opts rotateOptions
+		want []string
+	}{
+		{"keep all", rotateOptions{}, []string{"log20261010.log", "log20261014.log.gz", "log20261015.log", "log20261016.1.log"}},
+		{"max backups", rotateOptions{MaxBackups: 2}, []string{"log20261015.log", "log20261016.1.log"}},
+		{"max age", rotateOptions{MaxAge: 3}, []string{"log20261014.log.gz", "log20261015.log", "log20261016.1.log"}},
+		{"both", rotateOptions{MaxBackups: 3, MaxAge: 1}, []string{"log20261016.1.log"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWriter(t, tt.opts, &clock{t: day})
+			create(t, w, day, ages)
+
+			if err := w.millRunOnce(); err != nil {
+				t.Fatal(err)
+			}
+
+			got := names(t, w)
+			want := append(append([]string{}, tt.want...), always...)
+			sort.Strings(want)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestMillRunOnceCompresses(t *testing.T) {
+	w := newTestWriter(t, rotateOptions{Compress: true}, &clock{t: day})
+	write(t, w, "current\n")
+	create(t, w, day, map[string]time.Duration{"log20261015.log": 24 * time.Hour})
+	before, err := os.Stat(filepath.Join(w.dir, "log20261015.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.millRunOnce(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"log20261015.log.gz": "log20261015.log",
+		"log20261016.log":    "current\n",
+	}
+	if got := files(t, w); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	// the retention reads the modification time, compressing must not reset it
+	after, err := os.Stat(filepath.Join(w.dir, "log20261015.log.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.ModTime().Equal(before.ModTime()) {
+		t.Fatalf("compressed file modified at %v, want %v", after.ModTime(), before.ModTime())
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	w := newTestWriter(t, rotateOptions{}, &clock{t: day})
+	go w.millRun()
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
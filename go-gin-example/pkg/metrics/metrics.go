@@ -0,0 +1,157 @@
+// Package metrics 收集 Prometheus 指标，由 /metrics 暴露
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gin_blog"
+
+// NO_CODE labels responses that carry no e code, such as static files and 404s
+const NO_CODE = "none"
+
+// registry only holds our collectors, so importing packages that register on the
+// default registry doesn't change what we expose
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by route template, status and e code.",
+	}, []string{"method", "route", "status", "code"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route template and e code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "code"})
+
+	dbDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "gorm operation latency by operation and table.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "table"})
+
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "requests_total",
+		Help:      "Cache lookups of the services by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+
+	uploads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "uploads_total",
+		Help:      "Image uploads by e code.",
+	}, []string{"code"})
+
+	posters = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "poster_generations_total",
+		Help:      "Article poster generations by e code.",
+	}, []string{"code"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		dbDuration,
+		cacheRequests,
+		uploads,
+		posters,
+		redisPoolGauge("active_connections", "Connections in the Redis pool, idle or in use.", func(s redis.PoolStats) int {
+			return s.ActiveCount
+		}),
+		redisPoolGauge("idle_connections", "Idle connections in the Redis pool.", func(s redis.PoolStats) int {
+			return s.IdleCount
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "redis_pool",
+			Name:      "max_active_connections",
+			Help:      "[redis] MaxActive, 0 means unlimited.",
+		}, func() float64 {
+			if pool := redisPool.Load(); pool != nil {
+				return float64(pool.MaxActive)
+			}
+
+			return 0
+		}),
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTP records a served request, code is the e code of the response or NO_CODE
+func ObserveHTTP(method, route string, status int, code string, d time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status), code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(d.Seconds())
+}
+
+// ObserveDB records a gorm operation such as create or query on table
+func ObserveDB(operation, table string, d time.Duration) {
+	dbDuration.WithLabelValues(operation, table).Observe(d.Seconds())
+}
+
+// CacheHit and CacheMiss count the lookups of cache, e.g. "article"
+func CacheHit(cache string) {
+	cacheRequests.WithLabelValues(cache, "hit").Inc()
+}
+
+func CacheMiss(cache string) {
+	cacheRequests.WithLabelValues(cache, "miss").Inc()
+}
+
+// CountUpload and CountPoster count the results of image uploads and poster generations by e code
+func CountUpload(code int) {
+	uploads.WithLabelValues(strconv.Itoa(code)).Inc()
+}
+
+func CountPoster(code int) {
+	posters.WithLabelValues(strconv.Itoa(code)).Inc()
+}
+
+// redisPool is the pool reported by the redis_pool gauges, the registry is process wide so it is too
+var redisPool atomic.Pointer[redis.Pool]
+
+// SetRedisPool reports the stats of pool from now on
+func SetRedisPool(pool *redis.Pool) {
+	redisPool.Store(pool)
+}
+
+// redisPoolGauge reads the pool of SetRedisPool on every scrape, it is 0 before
+func redisPoolGauge(name, help string, value func(s redis.PoolStats) int) prometheus.GaugeFunc {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "redis_pool",
+		Name:      name,
+		Help:      help,
+	}, func() float64 {
+		pool := redisPool.Load()
+		if pool == nil {
+			return 0
+		}
+
+		return float64(value(pool.Stats()))
+	})
+}
@@ -0,0 +1,189 @@
+// Package migrate 版本化的数据库迁移
+// 每个迁移有递增的版本号和 Up/Down 两个方向，已执行的版本记录在 schema_migrations 表中
+package migrate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Migration is a single numbered schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// Record is a row of the schema_migrations table
+type Record struct {
+	Version   int    `gorm:"primary_key;auto_increment:false"`
+	Name      string `gorm:"type:varchar(255);default:''"`
+	AppliedOn int    `gorm:"default:0"`
+}
+
+// TABLE_NAME is the bookkeeping table, it is shared by every table prefix
+const TABLE_NAME = "schema_migrations"
+
+// TableName skips the prefix gorm.DefaultTableNameHandler would add
+func (Record) TableName() string {
+	return TABLE_NAME
+}
+
+// Status describes whether a migration has been applied
+type Status struct {
+	*Migration
+	Applied   bool
+	AppliedOn int
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []*Migration
+}
+
+// NewMigrator initialize instance, versions must be positive and unique
+func NewMigrator(db *gorm.DB, migrations []*Migration) (*Migrator, error) {
+	sorted := make([]*Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("migration %q has invalid version %d", m.Name, m.Version)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("duplicate migration version %d", m.Version)
+		}
+		if m.Up == nil || m.Down == nil {
+			return nil, fmt.Errorf("migration %d must define Up and Down", m.Version)
+		}
+	}
+
+	return &Migrator{db: db, migrations: sorted}, nil
+}
+
+// Up applies every pending migration in version order and returns the applied ones
+func (m *Migrator) Up() ([]*Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []*Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err := m.run(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+
+			return tx.Table(TABLE_NAME).Create(&Record{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedOn: int(time.Now().Unix()),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d %s up: %v", migration.Version, migration.Name, err)
+		}
+
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Down rolls back the latest applied migration, it returns nil when nothing is applied
+func (m *Migrator) Down() (*Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		err := m.run(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+
+			return tx.Table(TABLE_NAME).Where("version = ?", migration.Version).Delete(&Record{}).Error
+		})
+		if err != nil {
+			return nil, fmt.Errorf("migration %d %s down: %v", migration.Version, migration.Name, err)
+		}
+
+		return migration, nil
+	}
+
+	return nil, nil
+}
+
+// Status lists every known migration and whether it has been applied
+func (m *Migrator) Status() ([]*Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var list []*Status
+	for _, migration := range m.migrations {
+		record, ok := applied[migration.Version]
+		list = append(list, &Status{
+			Migration: migration,
+			Applied:   ok,
+			AppliedOn: record.AppliedOn,
+		})
+	}
+
+	return list, nil
+}
+
+// applied creates the schema_migrations table if needed and loads its rows
+func (m *Migrator) applied() (map[int]Record, error) {
+	if !m.db.HasTable(TABLE_NAME) {
+		if err := m.db.CreateTable(&Record{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var records []Record
+	if err := m.db.Table(TABLE_NAME).Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]Record, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+
+	return applied, nil
+}
+
+// run executes fn in a transaction, MySQL still commits DDL implicitly
+func (m *Migrator) run(fn func(tx *gorm.DB) error) error {
+	tx := m.db.Begin()
+	if err := tx.Error; err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
@@ -37,18 +37,18 @@ func NewQrCode(url string, width, height int, level qr.ErrorCorrectionLevel, mod
 }
 
 // GetQrCodePath get save path
-func GetQrCodePath() string {
-	return setting.AppSetting.QrCodeSavePath
+func GetQrCodePath(app *setting.App) string {
+	return app.QrCodeSavePath
 }
 
 // GetQrCodeFullPath get full save path
-func GetQrCodeFullPath() string {
-	return setting.AppSetting.RuntimeRootPath + setting.AppSetting.QrCodeSavePath
+func GetQrCodeFullPath(app *setting.App) string {
+	return app.RuntimeRootPath + GetQrCodePath(app)
 }
 
 // GetQrCodeFullUrl get the full access path
-func GetQrCodeFullUrl(name string) string {
-	return setting.AppSetting.PrefixUrl + "/" + GetQrCodePath() + name
+func GetQrCodeFullUrl(app *setting.App, name string) string {
+	return app.PrefixUrl + "/" + GetQrCodePath(app) + name
 }
 
 // GetQrCodeFileName get qr file name
@@ -0,0 +1,171 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/EGGYC/go-gin-example/models"
+)
+
+// fields of a Document, a match in the title weighs the most
+const (
+	FIELD_TITLE = iota
+	FIELD_DESC
+	FIELD_CONTENT
+
+	numFields
+)
+
+var fieldBoosts = [numFields]float64{FIELD_TITLE: 3, FIELD_DESC: 2, FIELD_CONTENT: 1}
+
+// BM25 parameters, k1 limits how much repeating a term counts and b how much a long
+// field is penalized
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Memory is an in-process inverted index ranking with BM25 per field.
+// It isn't shared between instances, each one loads the articles on startup and only
+// sees the writes it serves
+type Memory struct {
+	mu sync.RWMutex
+	// postings maps a term to the documents containing it and its count per field
+	postings map[string]map[int]*[numFields]int
+	docs     map[int]*docStats
+	// totalLengths sums the terms of every document per field, for the average length
+	totalLengths [numFields]int
+}
+
+type docStats struct {
+	lengths [numFields]int
+	terms   []string
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		postings: make(map[string]map[int]*[numFields]int),
+		docs:     make(map[int]*docStats),
+	}
+}
+
+// Load indexes the live articles of db
+func (m *Memory) Load(db *models.DB) error {
+	articles, err := db.GetArticles(0, 0, map[string]interface{}{"deleted_on": 0})
+	if err != nil {
+		return err
+	}
+
+	for _, article := range articles {
+		m.Put(&Document{ID: article.ID, Title: article.Title, Desc: article.Desc, Content: article.Content})
+	}
+
+	return nil
+}
+
+func (m *Memory) Put(doc *Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.delete(doc.ID)
+
+	stats := &docStats{}
+	for field, text := range [numFields]string{doc.Title, doc.Desc, doc.Content} {
+		for _, token := range Tokenize(text) {
+			counts := m.postings[token.Term]
+			if counts == nil {
+				counts = make(map[int]*[numFields]int)
+				m.postings[token.Term] = counts
+			}
+			count := counts[doc.ID]
+			if count == nil {
+				count = &[numFields]int{}
+				counts[doc.ID] = count
+				stats.terms = append(stats.terms, token.Term)
+			}
+			count[field]++
+			stats.lengths[field]++
+		}
+	}
+
+	m.docs[doc.ID] = stats
+	for field, length := range stats.lengths {
+		m.totalLengths[field] += length
+	}
+
+	return nil
+}
+
+func (m *Memory) Delete(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.delete(id)
+	return nil
+}
+
+// delete removes a document, mu must be held
+func (m *Memory) delete(id int) {
+	stats, ok := m.docs[id]
+	if !ok {
+		return
+	}
+
+	for _, term := range stats.terms {
+		delete(m.postings[term], id)
+		if len(m.postings[term]) == 0 {
+			delete(m.postings, term)
+		}
+	}
+	for field, length := range stats.lengths {
+		m.totalLengths[field] -= length
+	}
+	delete(m.docs, id)
+}
+
+// Search matches documents containing any term of query, the ones containing more of
+// them, rarer ones or in shorter fields rank first. Equal scores rank the newest first
+func (m *Memory) Search(query string, offset, limit int) ([]Hit, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	n := float64(len(m.docs))
+	var avgLengths [numFields]float64
+	for field, total := range m.totalLengths {
+		if n > 0 {
+			avgLengths[field] = float64(total) / n
+		}
+	}
+
+	scores := make(map[int]float64)
+	for term := range Terms(query) {
+		counts := m.postings[term]
+		df := float64(len(counts))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+		for id, count := range counts {
+			lengths := m.docs[id].lengths
+			for field, tf := range count {
+				if tf == 0 {
+					continue
+				}
+				norm := 1 - bm25B + bm25B*float64(lengths[field])/avgLengths[field]
+				scores[id] += fieldBoosts[field] * idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*norm)
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID > hits[j].ID
+	})
+
+	return page(hits, offset, limit), len(hits), nil
+}
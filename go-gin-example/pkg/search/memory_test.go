@@ -0,0 +1,85 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func ids(hits []Hit) []int {
+	var ids []int
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+
+	return ids
+}
+
+func TestMemorySearch(t *testing.T) {
+	m := NewMemory()
+	for _, doc := range []*Document{
+		{ID: 1, Title: "Gin middleware", Desc: "Writing middleware", Content: "A middleware wraps every handler of gin."},
+		{ID: 2, Title: "Redis cache", Desc: "Caching tags", Content: "Gin handlers read the cache before the database."},
+		{ID: 3, Title: "Cron jobs", Desc: "Purging deleted rows", Content: "Nothing about the web framework."},
+		{ID: 4, Title: "全文检索", Desc: "倒排索引", Content: "用倒排索引实现文章的全文检索。"},
+	} {
+		if err := m.Put(doc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		// a match in the title ranks first
+		{"gin", []int{1, 2}},
+		{"GIN Middleware", []int{1, 2}},
+		{"cache database", []int{2}},
+		{"检索", []int{4}},
+		{"kubernetes", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		hits, total, err := m.Search(tt.query, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids(hits); !reflect.DeepEqual(got, tt.want) || total != len(tt.want) {
+			t.Errorf("Search(%q) = %v of %d, want %v", tt.query, got, total, tt.want)
+		}
+	}
+
+	hits, total, _ := m.Search("gin", 1, 1)
+	if got := ids(hits); !reflect.DeepEqual(got, []int{2}) || total != 2 {
+		t.Errorf("second page of gin = %v of %d, want [2] of 2", got, total)
+	}
+
+	m.Put(&Document{ID: 1, Title: "Routing", Content: "Groups of routes"})
+	m.Delete(2)
+	if hits, total, _ := m.Search("gin", 0, 0); len(hits) != 0 || total != 0 {
+		t.Errorf("gin after editing 1 and deleting 2 = %v of %d, want none", ids(hits), total)
+	}
+	if hits, _, _ := m.Search("routes", 0, 0); !reflect.DeepEqual(ids(hits), []int{1}) {
+		t.Errorf("routes = %v, want [1]", ids(hits))
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text   string
+		query  string
+		length int
+		want   string
+	}{
+		{"Gin middleware", "gin", 0, "<em>Gin</em> middleware"},
+		{"用倒排索引实现全文检索", "全文检索", 0, "用倒排索引实现<em>全文检索</em>"},
+		{"<b>gin</b> & friends", "gin", 0, "&lt;b&gt;<em>gin</em>&lt;/b&gt; &amp; friends"},
+		{"nothing to see", "gin", 0, ""},
+		{"one two three four five six seven eight gin nine ten", "gin", 12, "…ht <em>gin</em> nine …"},
+	}
+	for _, tt := range tests {
+		if got := Highlight(tt.text, Terms(tt.query), tt.length); got != tt.want {
+			t.Errorf("Highlight(%q, %q, %d) = %q, want %q", tt.text, tt.query, tt.length, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package search
+
+import (
+	"github.com/EGGYC/go-gin-example/models"
+)
+
+// MySQL ranks articles with the FULLTEXT index of the article table in natural
+// language mode. MySQL keeps the index up to date, Put and Delete do nothing
+type MySQL struct {
+	db *models.DB
+}
+
+func NewMySQL(db *models.DB) *MySQL {
+	return &MySQL{db: db}
+}
+
+func (m *MySQL) Search(query string, offset, limit int) ([]Hit, int, error) {
+	scores, total, err := m.db.SearchArticles(query, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for _, score := range scores {
+		hits = append(hits, Hit{ID: score.ID, Score: score.Score})
+	}
+
+	return hits, total, nil
+}
+
+func (m *MySQL) Put(doc *Document) error {
+	return nil
+}
+
+func (m *MySQL) Delete(id int) error {
+	return nil
+}
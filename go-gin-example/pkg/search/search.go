@@ -0,0 +1,89 @@
+// Package search 文章全文检索接口及其实现：进程内倒排索引、MySQL FULLTEXT
+// 使用哪种实现由 app.ini 的 [search] Type 决定
+package search
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/EGGYC/go-gin-example/models"
+	"github.com/EGGYC/go-gin-example/pkg/setting"
+)
+
+// index types of [search] Type
+const (
+	TYPE_MEMORY = "memory"
+	TYPE_MYSQL  = "mysql"
+)
+
+// Document is the searchable text of an article
+type Document struct {
+	ID      int
+	Title   string
+	Desc    string
+	Content string
+}
+
+// Hit is an article matching the query, a higher Score ranks first
+type Hit struct {
+	ID    int
+	Score float64
+}
+
+// Index finds articles by the words of their title, description and content
+type Index interface {
+	// Search returns the hits of the page starting at offset ranked by relevance, and
+	// the number of hits of every page. limit <= 0 returns every hit
+	Search(query string, offset, limit int) (hits []Hit, total int, err error)
+	// Put adds the document or replaces the one with the same ID
+	Put(doc *Document) error
+	Delete(id int) error
+}
+
+// New returns the index configured in [search]. The memory index is filled with the
+// live articles of db, the mysql index needs the FULLTEXT index of the migrations
+func New(s *setting.Search, db *models.DB) (Index, error) {
+	switch strings.ToLower(s.Type) {
+	case TYPE_MEMORY, "":
+		m := NewMemory()
+		if err := m.Load(db); err != nil {
+			return nil, err
+		}
+		return m, nil
+	case TYPE_MYSQL:
+		if db.Dialect() != models.DIALECT_MYSQL {
+			return nil, fmt.Errorf("search type %q needs a mysql database, not %s", s.Type, db.Dialect())
+		}
+		return NewMySQL(db), nil
+	}
+
+	return nil, fmt.Errorf("unsupported search type: %q", s.Type)
+}
+
+// Nop finds nothing, Put and Delete are ignored
+type Nop struct{}
+
+func (Nop) Search(query string, offset, limit int) ([]Hit, int, error) {
+	return nil, 0, nil
+}
+
+func (Nop) Put(doc *Document) error {
+	return nil
+}
+
+func (Nop) Delete(id int) error {
+	return nil
+}
+
+// page returns the hits of the page starting at offset
+func page(hits []Hit, offset, limit int) []Hit {
+	if offset >= len(hits) {
+		return nil
+	}
+	hits = hits[offset:]
+	if limit > 0 && limit < len(hits) {
+		hits = hits[:limit]
+	}
+
+	return hits
+}
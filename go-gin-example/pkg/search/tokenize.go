@@ -0,0 +1,165 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token is a term of a text and where it was found, Start and End are byte offsets
+type Token struct {
+	Term  string
+	Start int
+	End   int
+}
+
+// Tokenize splits text into lowercase words of letters and digits. Chinese, Japanese
+// and Korean aren't separated by spaces, a run of them gives overlapping pairs of
+// characters like the ngram parser of MySQL: 全文检索 gives 全文, 文检 and 检索
+func Tokenize(text string) []Token {
+	var (
+		tokens []Token
+		// start of the current word, -1 outside of one
+		start = -1
+		// offsets of the characters of the current CJK run
+		cjk []int
+	)
+	flushCJK := func(end int) {
+		switch len(cjk) {
+		case 0:
+		case 1:
+			tokens = append(tokens, Token{Term: text[cjk[0]:end], Start: cjk[0], End: end})
+		default:
+			cjk = append(cjk, end)
+			for i := 0; i+2 < len(cjk); i++ {
+				tokens = append(tokens, Token{Term: text[cjk[i]:cjk[i+2]], Start: cjk[i], End: cjk[i+2]})
+			}
+		}
+		cjk = cjk[:0]
+	}
+	flushWord := func(end int) {
+		if start >= 0 {
+			tokens = append(tokens, Token{Term: strings.ToLower(text[start:end]), Start: start, End: end})
+			start = -1
+		}
+	}
+
+	for i, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord(i)
+			cjk = append(cjk, i)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK(i)
+			if start < 0 {
+				start = i
+			}
+		default:
+			flushWord(i)
+			flushCJK(i)
+		}
+	}
+	flushWord(len(text))
+	flushCJK(len(text))
+
+	return tokens
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// Terms returns the distinct terms of a query
+func Terms(query string) map[string]bool {
+	terms := make(map[string]bool)
+	for _, token := range Tokenize(query) {
+		terms[token.Term] = true
+	}
+
+	return terms
+}
+
+// highlight tags around the matched terms, the rest of a snippet is HTML escaped
+const (
+	HIGHLIGHT_PRE  = "<em>"
+	HIGHLIGHT_POST = "</em>"
+)
+
+// SNIPPET_LENGTH is the number of characters Highlight keeps of a long text
+const SNIPPET_LENGTH = 160
+
+// Highlight returns the part of text around the first match of terms, at most length
+// characters with … where it was cut, and every match wrapped in HIGHLIGHT_PRE and
+// HIGHLIGHT_POST. It returns "" when no term matches. length <= 0 keeps the whole text
+func Highlight(text string, terms map[string]bool, length int) string {
+	// byte ranges of the matches, overlapping pairs of CJK characters are merged
+	var ranges [][2]int
+	for _, token := range Tokenize(text) {
+		if !terms[token.Term] {
+			continue
+		}
+		if last := len(ranges) - 1; last >= 0 && token.Start <= ranges[last][1] {
+			if token.End > ranges[last][1] {
+				ranges[last][1] = token.End
+			}
+			continue
+		}
+		ranges = append(ranges, [2]int{token.Start, token.End})
+	}
+	if len(ranges) == 0 {
+		return ""
+	}
+
+	start, end := 0, len(text)
+	if length > 0 && utf8.RuneCountInString(text) > length {
+		// keep a quarter of the snippet before the first match
+		start = ranges[0][0]
+		for i := 0; i < length/4 && start > 0; i++ {
+			_, size := utf8.DecodeLastRuneInString(text[:start])
+			start -= size
+		}
+		end = start
+		for i := 0; i < length && end < len(text); i++ {
+			_, size := utf8.DecodeRuneInString(text[end:])
+			end += size
+		}
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, r := range ranges {
+		if r[1] <= start || r[0] >= end {
+			continue
+		}
+		from, to := max(r[0], start), min(r[1], end)
+		b.WriteString(html.EscapeString(text[pos:from]))
+		b.WriteString(HIGHLIGHT_PRE)
+		b.WriteString(html.EscapeString(text[from:to]))
+		b.WriteString(HIGHLIGHT_POST)
+		pos = to
+	}
+	b.WriteString(html.EscapeString(text[pos:end]))
+	if end < len(text) {
+		b.WriteString("…")
+	}
+
+	return b.String()
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,152 @@
+package setting
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/go-ini/ini"
+)
+
+// ENV_PREFIX starts the environment variables overriding a key, BLOG_<SECTION>_<KEY>
+// such as BLOG_APP_JWTSECRET or BLOG_DATABASE_PASSWORD. Section and key are case insensitive
+const ENV_PREFIX = "BLOG_"
+
+// ENV_NAME selects the per-environment file when -env isn't given
+const ENV_NAME = ENV_PREFIX + "ENV"
+
+const DEFAULT_CONFIG_FILE = "conf/app.ini"
+
+// Options are the layers of the configuration, each one overrides the previous:
+// ConfigFile, its per-environment file, BLOG_SECTION_KEY variables and Overrides
+type Options struct {
+	ConfigFile string
+	// Env loads conf/app.<Env>.ini next to ConfigFile when that file exists
+	Env string
+	// Overrides are section.key=value pairs given with -set
+	Overrides []string
+}
+
+// ParseFlags reads -config, -env and -set from args, it returns the arguments
+// left after the flags, such as the migrate subcommand
+func ParseFlags(args []string) (*Options, []string, error) {
+	opts := &Options{}
+	fs := flag.NewFlagSet("go-gin-example", flag.ContinueOnError)
+	fs.StringVar(&opts.ConfigFile, "config", DEFAULT_CONFIG_FILE, "configuration file")
+	fs.StringVar(&opts.Env, "env", os.Getenv(ENV_NAME), "environment, loads app.<env>.ini next to -config, defaults to $"+ENV_NAME)
+	fs.Var((*overrides)(&opts.Overrides), "set", "override a key, section.key=value, may be repeated")
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	return opts, fs.Args(), nil
+}
+
+type overrides []string
+
+func (o *overrides) String() string {
+	return strings.Join(*o, ",")
+}
+
+func (o *overrides) Set(value string) error {
+	*o = append(*o, value)
+	return nil
+}
+
+// load merges the layers of opts into one ini file
+func load(opts *Options) (*ini.File, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	name := opts.ConfigFile
+	if name == "" {
+		name = DEFAULT_CONFIG_FILE
+	}
+
+	cfg, err := ini.Load(name)
+	if err != nil {
+		return nil, fmt.Errorf("setting: fail to parse '%s': %v", name, err)
+	}
+
+	if opts.Env != "" {
+		envName := envFile(name, opts.Env)
+		if _, err := os.Stat(envName); err == nil {
+			if err := cfg.Append(envName); err != nil {
+				return nil, fmt.Errorf("setting: fail to parse '%s': %v", envName, err)
+			}
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("setting: %v", err)
+		}
+	}
+
+	for _, env := range os.Environ() {
+		pair := strings.SplitN(env, "=", 2)
+		if !strings.HasPrefix(pair[0], ENV_PREFIX) || pair[0] == ENV_NAME {
+			continue
+		}
+
+		// the environment isn't ours alone: Kubernetes adds BLOG_SERVICE_HOST, BLOG_PORT...
+		// for a Service named blog, so only a typo in -set is fatal
+		section, key, ok := splitEnvName(strings.TrimPrefix(pair[0], ENV_PREFIX))
+		if !ok {
+			log.Printf("setting: ignoring %s, it doesn't name a setting", pair[0])
+			continue
+		}
+		cfg.Section(section).Key(key).SetValue(pair[1])
+	}
+
+	for _, override := range opts.Overrides {
+		pair := strings.SplitN(override, "=", 2)
+		path := strings.SplitN(pair[0], ".", 2)
+		if len(pair) != 2 || len(path) != 2 {
+			return nil, fmt.Errorf("setting: -set %q isn't section.key=value", override)
+		}
+
+		section, key, ok := lookupKey(path[0], path[1])
+		if !ok {
+			return nil, fmt.Errorf("setting: -set %s doesn't name a setting", pair[0])
+		}
+		cfg.Section(section).Key(key).SetValue(pair[1])
+	}
+
+	return cfg, nil
+}
+
+// envFile returns conf/app.<env>.ini for conf/app.ini
+func envFile(name, env string) string {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + env + ext
+}
+
+// splitEnvName splits APP_JWTSECRET into the section and the key of the struct field
+func splitEnvName(name string) (section, key string, ok bool) {
+	parts := strings.SplitN(name, "_", 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+
+	return lookupKey(parts[0], parts[1])
+}
+
+// lookupKey finds the section and the field name of a key whatever their case,
+// keys that aren't fields of the section struct are unknown
+func lookupKey(section, key string) (string, string, bool) {
+	section = strings.ToLower(section)
+	v, ok := sections(&Snapshot{})[section]
+	if !ok {
+		return "", "", false
+	}
+
+	t := reflect.TypeOf(v).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		if strings.EqualFold(t.Field(i).Name, key) {
+			return section, t.Field(i).Name, true
+		}
+	}
+
+	return "", "", false
+}
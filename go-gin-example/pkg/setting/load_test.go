@@ -0,0 +1,49 @@
+package setting
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testOptions reads the repository's app.ini with a runtime root holding the font directory
+func testOptions(t *testing.T, overrides ...string) *Options {
+	t.Helper()
+
+	runtime := t.TempDir()
+	if err := os.Mkdir(filepath.Join(runtime, "fonts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return &Options{
+		ConfigFile: "../../conf/app.ini",
+		Overrides:  append([]string{"app.RuntimeRootPath=" + runtime + "/"}, overrides...),
+	}
+}
+
+func TestSetupIgnoresUnknownEnv(t *testing.T) {
+	// what Kubernetes adds for a Service named blog
+	t.Setenv("BLOG_SERVICE_HOST", "10.0.0.1")
+	t.Setenv("BLOG_SERVICE_PORT", "8000")
+	t.Setenv("BLOG_PORT", "tcp://10.0.0.1:8000")
+	t.Setenv("BLOG_PORT_8000_TCP_ADDR", "10.0.0.1")
+	t.Setenv("BLOG_APP_JWTSECRET", "env-secret-0123456789")
+	t.Setenv("BLOG_APP_PAGESIZE", "25")
+
+	if err := Setup(testOptions(t)); err != nil {
+		t.Fatal(err)
+	}
+	if s := Current(); s.App.JwtSecret != "env-secret-0123456789" || s.App.PageSize != 25 {
+		t.Errorf("got JwtSecret %q PageSize %d, want the BLOG_APP_* values", s.App.JwtSecret, s.App.PageSize)
+	}
+}
+
+func TestSetupRejectsUnknownOverride(t *testing.T) {
+	t.Setenv("BLOG_APP_JWTSECRET", "env-secret-0123456789")
+
+	err := Setup(testOptions(t, "service.host=10.0.0.1"))
+	if err == nil || !strings.Contains(err.Error(), "doesn't name a setting") {
+		t.Fatalf("got %v, want an unknown setting error", err)
+	}
+}
@@ -0,0 +1,184 @@
+package setting
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Snapshot is a complete, validated configuration. It is shared between goroutines
+// and must not be modified, slices included
+type Snapshot struct {
+	App      App
+	Server   Server
+	Database Database
+	Redis    Redis
+	Cron     Cron
+	Cache    Cache
+	Metrics  Metrics
+	Search   Search
+}
+
+// reloadable lists the keys Reload applies, every other setting is read once at
+// startup (ports, connections, paths...) and needs a restart
+var reloadable = map[string][]string{
+	"app": {"PageSize", "ImageAllowExts", "ImageMaxSize", "LogLevel"},
+}
+
+var (
+	current atomic.Pointer[Snapshot]
+
+	// mu serializes Setup and Reload, so subscribers see the snapshots in order
+	mu          sync.Mutex
+	setupOpts   *Options
+	subscribers []func(prev, next *Snapshot)
+)
+
+// Current returns the latest snapshot, code holding an older one, such as App.Config,
+// reads the reloadable settings from it. It is empty before Setup
+func Current() *Snapshot {
+	if s := current.Load(); s != nil {
+		return s
+	}
+
+	return &Snapshot{}
+}
+
+// OnReload calls fn with the previous and the new snapshot every time Setup or
+// Reload replaces it, prev is nil the first time
+func OnReload(fn func(prev, next *Snapshot)) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	subscribers = append(subscribers, fn)
+}
+
+// Reload reads the layers given to Setup again and applies the reloadable keys.
+// An invalid configuration is rejected and the current one kept. ignored lists the
+// keys that changed but need a restart, as section.Key
+func Reload() (ignored []string, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	next, err := build(setupOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	prev := Current()
+	merged := *prev
+	mergedSections := sections(&merged)
+	nextSections := sections(next)
+	prevSections := sections(prev)
+	for section := range mergedSections {
+		m := reflect.ValueOf(mergedSections[section]).Elem()
+		n := reflect.ValueOf(nextSections[section]).Elem()
+		p := reflect.ValueOf(prevSections[section]).Elem()
+
+		for i := 0; i < m.NumField(); i++ {
+			name := m.Type().Field(i).Name
+			if reflect.DeepEqual(p.Field(i).Interface(), n.Field(i).Interface()) {
+				continue
+			}
+			if !isReloadable(section, name) {
+				ignored = append(ignored, section+"."+name)
+				continue
+			}
+			m.Field(i).Set(n.Field(i))
+		}
+	}
+
+	store(&merged)
+
+	return ignored, nil
+}
+
+// store publishes s and notifies the subscribers, mu must be held
+func store(s *Snapshot) {
+	prev := current.Swap(s)
+	for _, fn := range subscribers {
+		fn(prev, s)
+	}
+}
+
+func isReloadable(section, key string) bool {
+	for _, k := range reloadable[section] {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WATCH_DEBOUNCE lets an editor finish writing before the file is read
+const WATCH_DEBOUNCE = 500 * time.Millisecond
+
+// Watch calls Reload when one of the files given to Setup changes, and reports the
+// result to onReload. Directories are watched rather than files so editors that
+// replace the file on save are noticed. Call stop to end watching
+func Watch(onReload func(ignored []string, err error)) (stop func() error, err error) {
+	mu.Lock()
+	opts := setupOpts
+	mu.Unlock()
+	if opts == nil {
+		return nil, fmt.Errorf("setting: Watch before Setup")
+	}
+
+	name := opts.ConfigFile
+	if name == "" {
+		name = DEFAULT_CONFIG_FILE
+	}
+	files := map[string]bool{filepath.Clean(name): true}
+	if opts.Env != "" {
+		files[filepath.Clean(envFile(name, opts.Env))] = true
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	dirs := map[string]bool{}
+	for f := range files {
+		dirs[filepath.Dir(f)] = true
+	}
+	for dir := range dirs {
+		if err := w.Add(dir); err != nil {
+			w.Close()
+			return nil, err
+		}
+	}
+
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if !files[filepath.Clean(event.Name)] || event.Op == fsnotify.Chmod {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(WATCH_DEBOUNCE, func() {
+					onReload(Reload())
+				})
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				onReload(nil, err)
+			}
+		}
+	}()
+
+	return w.Close, nil
+}
@@ -1,16 +1,19 @@
 package setting
 
 import (
-	"log"
+	"fmt"
+	"strings"
 	"time"
-
-	"github.com/go-ini/ini"
 )
 
 type App struct {
-	JwtSecret string
-	PageSize  int
-	PrefixUrl string
+	JwtSecret        string
+	JwtExpire        time.Duration
+	JwtRefreshExpire time.Duration
+	JwtTokenLookup   []string
+	JwtCookieName    string
+	PageSize         int
+	PrefixUrl        string
 
 	RuntimeRootPath string
 
@@ -22,23 +25,31 @@ type App struct {
 	QrCodeSavePath string
 	FontSavePath   string
 
-	LogSavePath string
-	LogSaveName string
-	LogFileExt  string
-	TimeFormat  string
-}
+	LogSavePath   string
+	LogSaveName   string
+	LogFileExt    string
+	LogMaxSize    int
+	LogMaxAge     int
+	LogMaxBackups int
+	LogCompress   bool
+	LogLevel      string
+	LogFormat     string
+	TimeFormat    string
 
-var AppSetting = &App{}
+	AccessLogSaveName string
+	AccessLogFormat   string
+}
 
 type Server struct {
-	RunMode      string
-	HttpPort     int
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
+	RunMode         string
+	HttpPort        int
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	DrainDelay      time.Duration
+	ShutdownTimeout time.Duration
+	WatchConfig     bool
 }
 
-var ServerSetting = &Server{}
-
 type Database struct {
 	Type        string
 	User        string
@@ -48,8 +59,6 @@ type Database struct {
 	TablePrefix string
 }
 
-var DatabaseSetting = &Database{}
-
 type Redis struct {
 	Host        string
 	Password    string
@@ -58,35 +67,121 @@ type Redis struct {
 	IdleTimeout time.Duration
 }
 
-var RedisSetting = &Redis{}
+type Cron struct {
+	PurgeSpec     string
+	RetentionDays int
+}
 
-var cfg *ini.File
+type Cache struct {
+	Type           string
+	MemoryCapacity int
+	TTL            time.Duration
+	TTLJitter      int
+	NegativeTTL    time.Duration
+	Coalesce       bool
+}
 
-// Setup initialize the configuration instance
-func Setup() {
-	var err error
-	cfg, err = ini.Load("conf/app.ini")
+type Metrics struct {
+	Token string
+}
+
+type Search struct {
+	Type string
+}
+
+// sections maps every ini section to the struct it is loaded into
+func sections(s *Snapshot) map[string]interface{} {
+	return map[string]interface{}{
+		"app":      &s.App,
+		"server":   &s.Server,
+		"database": &s.Database,
+		"redis":    &s.Redis,
+		"cron":     &s.Cron,
+		"cache":    &s.Cache,
+		"metrics":  &s.Metrics,
+		"search":   &s.Search,
+	}
+}
+
+// Setup loads the layers of opts, validates the result and only then replaces the
+// current settings, which are left untouched when it fails.
+// Reload reads the same layers again
+func Setup(opts *Options) error {
+	s, err := build(opts)
 	if err != nil {
-		log.Fatalf("setting.Setup, fail to parse 'conf/app.ini': %v", err)
+		return err
 	}
 
-	mapTo("app", AppSetting)
-	mapTo("server", ServerSetting)
-	mapTo("database", DatabaseSetting)
-	mapTo("redis", RedisSetting)
+	mu.Lock()
+	defer mu.Unlock()
+
+	setupOpts = opts
+	store(s)
 
-	AppSetting.ImageMaxSize = AppSetting.ImageMaxSize * 1024 * 1024
-	ServerSetting.ReadTimeout = ServerSetting.ReadTimeout * time.Second
-	ServerSetting.WriteTimeout = ServerSetting.WriteTimeout * time.Second
-	RedisSetting.IdleTimeout = RedisSetting.IdleTimeout * time.Second
+	return nil
 }
 
-// mapTo map section
-func mapTo(section string, v interface{}) {
-	err := cfg.Section(section).MapTo(v)
+// build loads, normalizes and validates a new snapshot
+func build(opts *Options) (*Snapshot, error) {
+	cfg, err := load(opts)
 	if err != nil {
-		log.Fatalf("Cfg.MapTo %s err: %v", section, err)
+		return nil, err
+	}
+
+	s := &Snapshot{}
+	for section, v := range sections(s) {
+		if err := cfg.Section(section).MapTo(v); err != nil {
+			return nil, fmt.Errorf("setting: map section %s: %v", section, err)
+		}
+	}
+	normalize(s)
+	if err := validate(s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// normalize converts the units used in the ini file and fills in defaults
+func normalize(s *Snapshot) {
+	s.App.ImageMaxSize = s.App.ImageMaxSize * 1024 * 1024
+	s.App.LogMaxSize = s.App.LogMaxSize * 1024 * 1024
+	s.App.JwtExpire = s.App.JwtExpire * time.Second
+	if s.App.JwtExpire <= 0 {
+		s.App.JwtExpire = 3 * time.Hour
+	}
+	s.App.JwtRefreshExpire = s.App.JwtRefreshExpire * time.Second
+	if s.App.JwtRefreshExpire <= 0 {
+		s.App.JwtRefreshExpire = 7 * 24 * time.Hour
+	}
+	if len(s.App.JwtTokenLookup) == 0 {
+		s.App.JwtTokenLookup = []string{"header", "cookie"}
+	}
+	for i, source := range s.App.JwtTokenLookup {
+		s.App.JwtTokenLookup[i] = strings.ToLower(strings.TrimSpace(source))
+	}
+	if s.App.LogLevel == "" {
+		s.App.LogLevel = "info"
+	}
+	if s.App.AccessLogSaveName == "" {
+		s.App.AccessLogSaveName = "access"
+	}
+	if s.App.JwtCookieName == "" {
+		s.App.JwtCookieName = "token"
+	}
+	s.Server.ReadTimeout = s.Server.ReadTimeout * time.Second
+	s.Server.WriteTimeout = s.Server.WriteTimeout * time.Second
+	s.Server.DrainDelay = s.Server.DrainDelay * time.Second
+	s.Server.ShutdownTimeout = s.Server.ShutdownTimeout * time.Second
+	if s.Server.ShutdownTimeout <= 0 {
+		s.Server.ShutdownTimeout = 5 * time.Second
+	}
+	s.Redis.IdleTimeout = s.Redis.IdleTimeout * time.Second
+	s.Cache.TTL = s.Cache.TTL * time.Second
+	if s.Cache.TTL <= 0 {
+		s.Cache.TTL = time.Hour
 	}
+	s.Cache.NegativeTTL = s.Cache.NegativeTTL * time.Second
 }
 
 //package setting
@@ -0,0 +1,48 @@
+package setting
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MIN_JWT_SECRET_LENGTH keeps HS256 secrets out of brute force range
+const MIN_JWT_SECRET_LENGTH = 16
+
+var jwtTokenLookups = map[string]bool{"header": true, "cookie": true, "query": true}
+
+// logLevels are the names logging.ParseLevel accepts
+var logLevels = map[string]bool{"debug": true, "info": true, "warn": true, "warning": true, "error": true, "fatal": true}
+
+// validate reports every problem of the merged settings at once
+func validate(s *Snapshot) error {
+	var errs []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(s.Server.HttpPort > 0 && s.Server.HttpPort <= 65535,
+		"[server] HttpPort %d is out of range 1-65535", s.Server.HttpPort)
+
+	check(len(s.App.JwtSecret) >= MIN_JWT_SECRET_LENGTH,
+		"[app] JwtSecret must be at least %d characters, set it with %sAPP_JWTSECRET", MIN_JWT_SECRET_LENGTH, ENV_PREFIX)
+	for _, source := range s.App.JwtTokenLookup {
+		check(jwtTokenLookups[source], "[app] JwtTokenLookup: unknown source %q", source)
+	}
+
+	check(logLevels[strings.ToLower(strings.TrimSpace(s.App.LogLevel))], "[app] LogLevel: unknown level %q", s.App.LogLevel)
+	check(s.App.PageSize > 0, "[app] PageSize must be positive")
+
+	fontPath := s.App.RuntimeRootPath + s.App.FontSavePath
+	info, err := os.Stat(fontPath)
+	check(err == nil && info.IsDir(), "[app] font path %s isn't a directory", fontPath)
+
+	if len(errs) > 0 {
+		return errors.New("setting: invalid configuration: " + strings.Join(errs, "; "))
+	}
+
+	return nil
+}
@@ -20,8 +20,8 @@ import (
 )
 
 // GetImageFullUrl 获取图片完整访问URL
-func GetImageFullUrl(name string) string {
-	return setting.AppSetting.PrefixUrl + "/" + GetImagePath() + name
+func GetImageFullUrl(app *setting.App, name string) string {
+	return app.PrefixUrl + "/" + GetImagePath(app) + name
 }
 
 // GetImageName 获取图片名称
@@ -34,20 +34,20 @@ func GetImageName(name string) string {
 }
 
 // GetImagePath 返回图片路径
-func GetImagePath() string {
-	return setting.AppSetting.ImageSavePath
+func GetImagePath(app *setting.App) string {
+	return app.ImageSavePath
 }
 
 // GetImageFullPath 获取图片完整路径
-func GetImageFullPath() string {
-	return setting.AppSetting.RuntimeRootPath + GetImagePath()
+func GetImageFullPath(app *setting.App) string {
+	return app.RuntimeRootPath + GetImagePath(app)
 }
 
-// CheckImageExt 检查图片后缀
-func CheckImageExt(fileName string) bool {
+// CheckImageExt 检查图片后缀，ImageAllowExts 可重新加载，由调用方传入当前的设置
+func CheckImageExt(app *setting.App, fileName string) bool {
 	ext := file.GetExt(fileName)
-	for _, allowExt := range setting.AppSetting.ImageAllowExts {
-		if strings.ToUpper(allowExt) == strings.ToUpper(ext) {
+	for _, allowExt := range app.ImageAllowExts {
+		if strings.EqualFold(allowExt, ext) {
 			return true
 		}
 	}
@@ -55,8 +55,8 @@ func CheckImageExt(fileName string) bool {
 	return false
 }
 
-// CheckImageSize 检查图片大小
-func CheckImageSize(f multipart.File) bool {
+// CheckImageSize 检查图片大小，ImageMaxSize 同样取自传入的设置
+func CheckImageSize(app *setting.App, f multipart.File) bool {
 	size, err := file.GetSize(f)
 	if err != nil {
 		log.Println(err)
@@ -64,7 +64,7 @@ func CheckImageSize(f multipart.File) bool {
 		return false
 	}
 
-	return size <= setting.AppSetting.ImageMaxSize
+	return size <= app.ImageMaxSize
 }
 
 // CheckImage 检查图片
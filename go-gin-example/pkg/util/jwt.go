@@ -4,6 +4,9 @@
 package util
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
@@ -11,36 +14,103 @@ import (
 	"github.com/EGGYC/go-gin-example/pkg/setting"
 )
 
-var jwtSecret = []byte(setting.AppSetting.JwtSecret)
+// token types, only access tokens are accepted by the API
+const (
+	TOKEN_ACCESS  = "access"
+	TOKEN_REFRESH = "refresh"
+)
 
+// Claims identify the user, the token ID (jti) lets a single token be revoked and
+// the version of the account revokes all of them at once
 type Claims struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	UserID  int      `json:"uid"`
+	Roles   []string `json:"roles,omitempty"`
+	Type    string   `json:"typ"`
+	Version int      `json:"ver"`
 	jwt.StandardClaims
 }
 
-func GenerateToken(username, password string) (string, error) {
+// TokenPair is returned by a login or a refresh
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// TokenSigner signs and parses the tokens of the API with the secret of [app] JwtSecret
+type TokenSigner struct {
+	secret        []byte
+	accessExpire  time.Duration
+	refreshExpire time.Duration
+}
+
+// NewTokenSigner reads the secret and the lifetimes from s, it refuses an empty secret
+// so a token can't be signed before the settings are loaded
+func NewTokenSigner(s *setting.App) (*TokenSigner, error) {
+	if s.JwtSecret == "" {
+		return nil, errors.New("util: empty JWT secret")
+	}
+
+	return &TokenSigner{
+		secret:        []byte(s.JwtSecret),
+		accessExpire:  s.JwtExpire,
+		refreshExpire: s.JwtRefreshExpire,
+	}, nil
+}
+
+// Generate signs a token of the given type that expires after ttl, version is the
+// token version of the account
+func (t *TokenSigner) Generate(userID int, roles []string, version int, typ string, ttl time.Duration) (string, error) {
+	jti, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
 	nowTime := time.Now()
-	expireTime := nowTime.Add(3 * time.Hour)
+	expireTime := nowTime.Add(ttl)
 
 	claims := Claims{
-		username,
-		password,
+		userID,
+		roles,
+		typ,
+		version,
 		jwt.StandardClaims{
+			Id:        jti,
+			IssuedAt:  nowTime.Unix(),
 			ExpiresAt: expireTime.Unix(),
 			Issuer:    "gin-blog",
 		},
 	}
 
 	tokenClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	token, err := tokenClaims.SignedString(jwtSecret)
+	token, err := tokenClaims.SignedString(t.secret)
 
 	return token, err
 }
 
-func ParseToken(token string) (*Claims, error) {
+// GeneratePair signs an access token and a refresh token with the configured lifetimes
+func (t *TokenSigner) GeneratePair(userID int, roles []string, version int) (*TokenPair, error) {
+	accessToken, err := t.Generate(userID, roles, version, TOKEN_ACCESS, t.accessExpire)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := t.Generate(userID, roles, version, TOKEN_REFRESH, t.refreshExpire)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(t.accessExpire / time.Second),
+	}, nil
+}
+
+// Parse verifies the signature of token and returns its claims
+func (t *TokenSigner) Parse(token string) (*Claims, error) {
 	tokenClaims, err := jwt.ParseWithClaims(token, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
+		return t.secret, nil
 	})
 
 	if tokenClaims != nil {
@@ -52,6 +122,16 @@ func ParseToken(token string) (*Claims, error) {
 	return nil, err
 }
 
+// newTokenID returns a random jti
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
 //NewWithClaims(method SigningMethod, claims Claims)，method对应着SigningMethodHMAC  struct{}，其包含SigningMethodHS256、SigningMethodHS384、SigningMethodHS512三种crypto.Hash方案
 //func (t *Token) SignedString(key interface{}) 该方法内部生成签名字符串，再用于获取完整、已签名的token
 //func (p *Parser) ParseWithClaims 用于解析鉴权的声明，方法内部主要是具体的解码和校验的过程，最终返回*Token
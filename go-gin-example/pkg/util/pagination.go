@@ -4,17 +4,15 @@ package util
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/unknwon/com"
-
-	"github.com/EGGYC/go-gin-example/pkg/setting"
 )
 
-// GetPage 分页工具包 实现获取分页参数，传入page后根据setting中的设置得到res，res为models中的db查询时offset的数量
-// GetPage 分页页码的获取方法
-func GetPage(c *gin.Context) int {
+// GetPage 分页工具包 实现获取分页参数，传入page后根据每页条数得到res，res为models中的db查询时offset的数量
+// GetPage 分页页码的获取方法，pageSize 须与查询使用的每页条数来自同一份设置
+func GetPage(c *gin.Context, pageSize int) int {
 	result := 0
 	page, _ := com.StrTo(c.Query("page")).Int()
 	if page > 0 {
-		result = (page - 1) * setting.AppSetting.PageSize
+		result = (page - 1) * pageSize
 	}
 
 	return result
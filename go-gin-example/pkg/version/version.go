@@ -0,0 +1,49 @@
+// Package version 构建信息，编译时通过 ldflags 写入：
+//
+//	go build -ldflags "-X github.com/EGGYC/go-gin-example/pkg/version.Version=v1.2.0 \
+//	  -X github.com/EGGYC/go-gin-example/pkg/version.Commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/EGGYC/go-gin-example/pkg/version.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// set through -ldflags "-X", see the package comment
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build info, the commit and commit time recorded by the go command
+// fill in what ldflags didn't set
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range build.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = s.Value
+			}
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/EGGYC/go-gin-example/pkg/lifecycle"
+	"github.com/EGGYC/go-gin-example/pkg/logging"
+	"github.com/EGGYC/go-gin-example/pkg/setting"
+)
+
+// setupReload reloads the settings on SIGHUP and, with [server] WatchConfig, when the
+// conf files change
+func setupReload(lc *lifecycle.Manager, cfg *setting.Snapshot) error {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-hup:
+				logging.Info("received SIGHUP, reloading settings")
+				reported(setting.Reload())
+			case <-done:
+				return
+			}
+		}
+	}()
+	lc.Add("reload", func(ctx context.Context) error {
+		signal.Stop(hup)
+		close(done)
+		return nil
+	})
+
+	if !cfg.Server.WatchConfig {
+		return nil
+	}
+
+	stop, err := setting.Watch(reported)
+	if err != nil {
+		return err
+	}
+	lc.Add("config watcher", func(ctx context.Context) error {
+		return stop()
+	})
+
+	return nil
+}
+
+// reported logs the outcome of a reload, a rejected configuration leaves the current one in place
+func reported(ignored []string, err error) {
+	if err != nil {
+		logging.Error("settings not reloaded, keeping the current ones:", err)
+		return
+	}
+	if len(ignored) > 0 {
+		logging.Warn("settings reloaded, these changes need a restart:", ignored)
+		return
+	}
+
+	logging.Info("settings reloaded")
+}
@@ -2,10 +2,12 @@ package api
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/astaxie/beego/validation"
 	"github.com/gin-gonic/gin"
 
+	"github.com/EGGYC/go-gin-example/middleware/jwt"
 	"github.com/EGGYC/go-gin-example/pkg/app"
 	"github.com/EGGYC/go-gin-example/pkg/e"
 	"github.com/EGGYC/go-gin-example/pkg/util"
@@ -24,7 +26,7 @@ type auth struct {
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /auth [get]
-func GetAuth(c *gin.Context) {
+func (h *Handler) GetAuth(c *gin.Context) {
 	appG := app.Gin{C: c}
 	valid := validation.Validation{}
 
@@ -40,7 +42,7 @@ func GetAuth(c *gin.Context) {
 		return
 	}
 
-	authService := auth_service.Auth{Username: username, Password: password}
+	authService := auth_service.Auth{Service: h.Auth, Username: username, Password: password}
 	isExist, err := authService.Check()
 	if err != nil {
 		appG.Response(http.StatusInternalServerError, e.ERROR_AUTH_CHECK_TOKEN_FAIL, nil)
@@ -52,15 +54,242 @@ func GetAuth(c *gin.Context) {
 		return
 	}
 
-	token, err := util.GenerateToken(username, password)
+	tokens, err := h.Tokens.GeneratePair(authService.ID, []string{authService.Role}, authService.TokenVersion)
 	if err != nil {
 		appG.Response(http.StatusInternalServerError, e.ERROR_AUTH_TOKEN, nil)
 		return
 	}
 
-	appG.Response(http.StatusOK, e.SUCCESS, map[string]string{
-		"token": token,
-	})
+	appG.Response(http.StatusOK, e.SUCCESS, tokens)
+}
+
+type RefreshForm struct {
+	RefreshToken string `form:"refresh_token" valid:"Required"`
+}
+
+// @Summary Exchange a refresh token for a new token pair
+// @Produce  json
+// @Param refresh_token body string true "RefreshToken"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /auth/refresh [post]
+func (h *Handler) RefreshToken(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form RefreshForm
+	)
+
+	httpCode, errCode := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, nil)
+		return
+	}
+
+	claims, code := h.parseRefreshToken(&appG, form.RefreshToken)
+	if code != e.SUCCESS {
+		appG.Response(http.StatusUnauthorized, code, nil)
+		return
+	}
+
+	authService := auth_service.Auth{Service: h.Auth, ID: claims.UserID}
+	auth, err := authService.Get()
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_AUTH_CHECK_TOKEN_FAIL, nil)
+		return
+	}
+	if auth == nil {
+		appG.Response(http.StatusUnauthorized, e.ERROR_AUTH, nil)
+		return
+	}
+	// a password change revokes every token issued before it
+	if auth.TokenVersion != claims.Version {
+		appG.Response(http.StatusUnauthorized, e.ERROR_AUTH_TOKEN_REVOKED, nil)
+		return
+	}
+
+	// a refresh token is single use, the new pair replaces it. Consume checks and
+	// revokes in one step, so of concurrent refreshes with the same token only one passes
+	consumed, err := h.Denylist.Consume(claims.Id, claims.ExpiresAt)
+	if err != nil {
+		appG.Logger().Warn("denylist.Consume err:", err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_AUTH_TOKEN, nil)
+		return
+	}
+	if !consumed {
+		appG.Response(http.StatusUnauthorized, e.ERROR_AUTH_TOKEN_REVOKED, nil)
+		return
+	}
+
+	// the role is reloaded, so a role change applies from the next refresh
+	tokens, err := h.Tokens.GeneratePair(auth.ID, []string{auth.Role}, auth.TokenVersion)
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_AUTH_TOKEN, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, tokens)
+}
+
+type LogoutForm struct {
+	RefreshToken string `form:"refresh_token"`
+}
+
+// @Summary Revoke the current token and optionally its refresh token
+// @Produce  json
+// @Param refresh_token body string false "RefreshToken"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /auth/logout [post]
+func (h *Handler) Logout(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form LogoutForm
+	)
+
+	httpCode, errCode := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, nil)
+		return
+	}
+
+	claims := jwt.GetClaims(c)
+	if form.RefreshToken != "" {
+		refresh, code := h.parseRefreshToken(&appG, form.RefreshToken)
+		if code != e.SUCCESS || refresh.UserID != claims.UserID {
+			appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, nil)
+			return
+		}
+
+		if err := h.Denylist.Revoke(refresh.Id, refresh.ExpiresAt); err != nil {
+			appG.Logger().Warn("denylist.Revoke err:", err)
+			appG.Response(http.StatusInternalServerError, e.ERROR_AUTH_LOGOUT_FAIL, nil)
+			return
+		}
+	}
+
+	if err := h.Denylist.Revoke(claims.Id, claims.ExpiresAt); err != nil {
+		appG.Logger().Warn("denylist.Revoke err:", err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_AUTH_LOGOUT_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, nil)
+}
+
+// parseRefreshToken returns the claims of a valid, unrevoked refresh token
+func (h *Handler) parseRefreshToken(appG *app.Gin, token string) (*util.Claims, int) {
+	claims, err := h.Tokens.Parse(token)
+	if err != nil || claims.Type != util.TOKEN_REFRESH {
+		return nil, e.ERROR_AUTH_CHECK_TOKEN_FAIL
+	}
+	if time.Now().Unix() > claims.ExpiresAt {
+		return nil, e.ERROR_AUTH_CHECK_TOKEN_TIMEOUT
+	}
+
+	revoked, err := h.Denylist.IsRevoked(claims.Id)
+	if err != nil {
+		appG.Logger().Warn("denylist.IsRevoked err:", err)
+		return nil, e.ERROR_AUTH_CHECK_TOKEN_FAIL
+	}
+	if revoked {
+		return nil, e.ERROR_AUTH_TOKEN_REVOKED
+	}
+
+	return claims, e.SUCCESS
+}
+
+type RegisterForm struct {
+	Username string `form:"username" valid:"Required;MaxSize(50)"`
+	Password string `form:"password" valid:"Required;MinSize(6);MaxSize(50)"`
+}
+
+// @Summary Register
+// @Produce  json
+// @Param username body string true "userName"
+// @Param password body string true "password"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /auth/register [post]
+func (h *Handler) Register(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form RegisterForm
+	)
+
+	httpCode, errCode := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, nil)
+		return
+	}
+
+	authService := auth_service.Auth{Service: h.Auth, Username: form.Username, Password: form.Password}
+	exists, err := authService.ExistByUsername()
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_EXIST_AUTH_FAIL, nil)
+		return
+	}
+	if exists {
+		appG.Response(http.StatusOK, e.ERROR_EXIST_AUTH, nil)
+		return
+	}
+
+	if err := authService.Register(); err != nil {
+		appG.Logger().Warn(err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_ADD_AUTH_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, nil)
+}
+
+type ChangePasswordForm struct {
+	OldPassword string `form:"old_password" valid:"Required;MaxSize(50)"`
+	NewPassword string `form:"new_password" valid:"Required;MinSize(6);MaxSize(50)"`
+}
+
+// @Summary Change the password of the current user, the tokens issued before are revoked
+// @Produce  json
+// @Param old_password body string true "OldPassword"
+// @Param new_password body string true "NewPassword"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /auth/password [put]
+func (h *Handler) ChangePassword(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form ChangePasswordForm
+	)
+
+	httpCode, errCode := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, nil)
+		return
+	}
+
+	claims := jwt.GetClaims(c)
+	authService := auth_service.Auth{
+		Service:     h.Auth,
+		ID:          claims.UserID,
+		Password:    form.OldPassword,
+		NewPassword: form.NewPassword,
+	}
+	ok, err := authService.Check()
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_AUTH_CHECK_TOKEN_FAIL, nil)
+		return
+	}
+	if !ok {
+		appG.Response(http.StatusUnauthorized, e.ERROR_AUTH_PASSWORD, nil)
+		return
+	}
+
+	if err := authService.ChangePassword(); err != nil {
+		appG.Logger().Warn(err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EDIT_AUTH_PASSWORD_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, nil)
 }
 
 //
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/EGGYC/go-gin-example/pkg/app"
+	"github.com/EGGYC/go-gin-example/pkg/cache"
+	"github.com/EGGYC/go-gin-example/pkg/e"
+	"github.com/EGGYC/go-gin-example/pkg/file"
+	"github.com/EGGYC/go-gin-example/pkg/gredis"
+	"github.com/EGGYC/go-gin-example/pkg/health"
+	"github.com/EGGYC/go-gin-example/pkg/version"
+)
+
+// readinessChecks are the dependencies a request may need. Redis is only required
+// when the cache or the denylist was built on it, otherwise both run without it and
+// a Redis that is down only degrades the instance
+func (h *Handler) readinessChecks() []health.Check {
+	_, redisCache := h.Cache.(*cache.Redis)
+
+	return []health.Check{
+		{Name: "database", Fn: h.DB.Ping},
+		{Name: "redis", Fn: func(ctx context.Context) error {
+			return gredis.PingContext(ctx, h.Redis)
+		}, Optional: !redisCache && !h.Denylist.UsesRedis()},
+		{Name: "runtime", Fn: func(ctx context.Context) error {
+			return file.CheckWritable(h.Config.App.RuntimeRootPath)
+		}},
+	}
+}
+
+// @Summary Liveness probe
+// @Produce  json
+// @Success 200 {object} app.Response
+// @Router /healthz [get]
+func Healthz(c *gin.Context) {
+	appG := app.Gin{C: c}
+	appG.Response(http.StatusOK, e.SUCCESS, nil)
+}
+
+// @Summary Readiness probe, fails once the graceful shutdown started
+// @Produce  json
+// @Success 200 {object} app.Response
+// @Failure 503 {object} app.Response
+// @Router /readyz [get]
+func (h *Handler) Readyz(c *gin.Context) {
+	appG := app.Gin{C: c}
+	ready, results := health.Ready(c.Request.Context(), h.readinessChecks())
+	if !ready {
+		appG.Response(http.StatusServiceUnavailable, e.ERROR, results)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, results)
+}
+
+// @Summary Build info
+// @Produce  json
+// @Success 200 {object} app.Response
+// @Router /version [get]
+func Version(c *gin.Context) {
+	appG := app.Gin{C: c}
+	appG.Response(http.StatusOK, e.SUCCESS, version.Get())
+}
@@ -7,7 +7,7 @@ import (
 
 	"github.com/EGGYC/go-gin-example/pkg/app"
 	"github.com/EGGYC/go-gin-example/pkg/e"
-	"github.com/EGGYC/go-gin-example/pkg/logging"
+	"github.com/EGGYC/go-gin-example/pkg/metrics"
 	"github.com/EGGYC/go-gin-example/pkg/upload"
 )
 
@@ -17,11 +17,17 @@ import (
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /api/v1/tags/import [post]
-func UploadImage(c *gin.Context) {
+func (h *Handler) UploadImage(c *gin.Context) {
 	appG := app.Gin{C: c}
+	defer func() {
+		if code, ok := appG.Code(); ok {
+			metrics.CountUpload(code)
+		}
+	}()
+
 	file, image, err := c.Request.FormFile("image")
 	if err != nil {
-		logging.Warn(err)
+		appG.Logger().Warn(err)
 		appG.Response(http.StatusInternalServerError, e.ERROR, nil)
 		return
 	}
@@ -31,31 +37,32 @@ func UploadImage(c *gin.Context) {
 		return
 	}
 
+	cfg := h.Settings()
 	imageName := upload.GetImageName(image.Filename)
-	fullPath := upload.GetImageFullPath()
-	savePath := upload.GetImagePath()
+	fullPath := upload.GetImageFullPath(&cfg.App)
+	savePath := upload.GetImagePath(&cfg.App)
 	src := fullPath + imageName
 
-	if !upload.CheckImageExt(imageName) || !upload.CheckImageSize(file) {
+	if !upload.CheckImageExt(&cfg.App, imageName) || !upload.CheckImageSize(&cfg.App, file) {
 		appG.Response(http.StatusBadRequest, e.ERROR_UPLOAD_CHECK_IMAGE_FORMAT, nil)
 		return
 	}
 
 	err = upload.CheckImage(fullPath)
 	if err != nil {
-		logging.Warn(err)
+		appG.Logger().Warn(err)
 		appG.Response(http.StatusInternalServerError, e.ERROR_UPLOAD_CHECK_IMAGE_FAIL, nil)
 		return
 	}
 
 	if err := c.SaveUploadedFile(image, src); err != nil {
-		logging.Warn(err)
+		appG.Logger().Warn(err)
 		appG.Response(http.StatusInternalServerError, e.ERROR_UPLOAD_SAVE_IMAGE_FAIL, nil)
 		return
 	}
 
 	appG.Response(http.StatusOK, e.SUCCESS, map[string]string{
-		"image_url":      upload.GetImageFullUrl(imageName),
+		"image_url":      upload.GetImageFullUrl(&cfg.App, imageName),
 		"image_save_url": savePath + imageName,
 	})
 }
@@ -2,16 +2,20 @@ package v1
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/astaxie/beego/validation"
 	"github.com/boombuler/barcode/qr"
 	"github.com/gin-gonic/gin"
 	"github.com/unknwon/com"
 
+	"github.com/EGGYC/go-gin-example/middleware/permission"
 	"github.com/EGGYC/go-gin-example/pkg/app"
 	"github.com/EGGYC/go-gin-example/pkg/e"
+	"github.com/EGGYC/go-gin-example/pkg/export"
+	"github.com/EGGYC/go-gin-example/pkg/metrics"
 	"github.com/EGGYC/go-gin-example/pkg/qrcode"
-	"github.com/EGGYC/go-gin-example/pkg/setting"
 	"github.com/EGGYC/go-gin-example/pkg/util"
 	"github.com/EGGYC/go-gin-example/service/article_service"
 	"github.com/EGGYC/go-gin-example/service/tag_service"
@@ -23,7 +27,7 @@ import (
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /api/v1/articles/{id} [get]
-func GetArticle(c *gin.Context) {
+func (h *Handler) GetArticle(c *gin.Context) {
 	appG := app.Gin{C: c}
 	id := com.StrTo(c.Param("id")).MustInt()
 	valid := validation.Validation{}
@@ -35,23 +39,17 @@ func GetArticle(c *gin.Context) {
 		return
 	}
 
-	articleService := article_service.Article{ID: id}
-	exists, err := articleService.ExistByID()
+	articleService := article_service.Article{Service: h.Articles, ID: id}
+	article, err := articleService.Get()
 	if err != nil {
-		appG.Response(http.StatusInternalServerError, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, nil)
+		appG.Response(http.StatusInternalServerError, e.ERROR_GET_ARTICLE_FAIL, nil)
 		return
 	}
-	if !exists {
+	if article == nil {
 		appG.Response(http.StatusOK, e.ERROR_NOT_EXIST_ARTICLE, nil)
 		return
 	}
 
-	article, err := articleService.Get()
-	if err != nil {
-		appG.Response(http.StatusInternalServerError, e.ERROR_GET_ARTICLE_FAIL, nil)
-		return
-	}
-
 	appG.Response(http.StatusOK, e.SUCCESS, article)
 }
 
@@ -63,7 +61,7 @@ func GetArticle(c *gin.Context) {
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /api/v1/articles [get]
-func GetArticles(c *gin.Context) {
+func (h *Handler) GetArticles(c *gin.Context) {
 	appG := app.Gin{C: c}
 	valid := validation.Validation{}
 
@@ -85,11 +83,13 @@ func GetArticles(c *gin.Context) {
 		return
 	}
 
+	cfg := h.Settings()
 	articleService := article_service.Article{
+		Service:  h.Articles,
 		TagID:    tagId,
 		State:    state,
-		PageNum:  util.GetPage(c),
-		PageSize: setting.AppSetting.PageSize,
+		PageNum:  util.GetPage(c, cfg.App.PageSize),
+		PageSize: cfg.App.PageSize,
 	}
 
 	total, err := articleService.Count()
@@ -111,12 +111,50 @@ func GetArticles(c *gin.Context) {
 	appG.Response(http.StatusOK, e.SUCCESS, data)
 }
 
+// @Summary Search articles
+// @Produce  json
+// @Param q query string true "Words to find in the title, desc or content"
+// @Param page query int false "Page"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /api/v1/articles/search [get]
+func (h *Handler) SearchArticles(c *gin.Context) {
+	appG := app.Gin{C: c}
+	valid := validation.Validation{}
+	q := strings.TrimSpace(c.Query("q"))
+	valid.Required(q, "q")
+	valid.MaxSize(q, 100, "q")
+
+	if valid.HasErrors() {
+		app.MarkErrors(valid.Errors)
+		appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, nil)
+		return
+	}
+
+	cfg := h.Settings()
+	articleService := article_service.Article{
+		Service:  h.Articles,
+		PageNum:  util.GetPage(c, cfg.App.PageSize),
+		PageSize: cfg.App.PageSize,
+	}
+	results, total, err := articleService.Search(q)
+	if err != nil {
+		appG.Logger().Warn(err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_SEARCH_ARTICLES_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]interface{}{
+		"lists": results,
+		"total": total,
+	})
+}
+
 type AddArticleForm struct {
 	TagID         int    `form:"tag_id" valid:"Required;Min(1)"`
 	Title         string `form:"title" valid:"Required;MaxSize(100)"`
 	Desc          string `form:"desc" valid:"Required;MaxSize(255)"`
 	Content       string `form:"content" valid:"Required;MaxSize(65535)"`
-	CreatedBy     string `form:"created_by" valid:"Required;MaxSize(100)"`
 	CoverImageUrl string `form:"cover_image_url" valid:"Required;MaxSize(255)"`
 	State         int    `form:"state" valid:"Range(0,1)"`
 }
@@ -127,12 +165,11 @@ type AddArticleForm struct {
 // @Param title body string true "Title"
 // @Param desc body string true "Desc"
 // @Param content body string true "Content"
-// @Param created_by body string true "CreatedBy"
 // @Param state body int true "State"
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /api/v1/articles [post]
-func AddArticle(c *gin.Context) {
+func (h *Handler) AddArticle(c *gin.Context) {
 	var (
 		appG = app.Gin{C: c}
 		form AddArticleForm
@@ -144,7 +181,7 @@ func AddArticle(c *gin.Context) {
 		return
 	}
 
-	tagService := tag_service.Tag{ID: form.TagID}
+	tagService := tag_service.Tag{Service: h.Tags, ID: form.TagID}
 	exists, err := tagService.ExistByID()
 	if err != nil {
 		appG.Response(http.StatusInternalServerError, e.ERROR_EXIST_TAG_FAIL, nil)
@@ -156,14 +193,20 @@ func AddArticle(c *gin.Context) {
 		return
 	}
 
+	createdBy, ok := h.currentUsername(&appG)
+	if !ok {
+		return
+	}
+
 	articleService := article_service.Article{
+		Service:       h.Articles,
 		TagID:         form.TagID,
 		Title:         form.Title,
 		Desc:          form.Desc,
 		Content:       form.Content,
 		CoverImageUrl: form.CoverImageUrl,
 		State:         form.State,
-		CreatedBy:     form.CreatedBy,
+		CreatedBy:     createdBy,
 	}
 	if err := articleService.Add(); err != nil {
 		appG.Response(http.StatusInternalServerError, e.ERROR_ADD_ARTICLE_FAIL, nil)
@@ -179,7 +222,6 @@ type EditArticleForm struct {
 	Title         string `form:"title" valid:"Required;MaxSize(100)"`
 	Desc          string `form:"desc" valid:"Required;MaxSize(255)"`
 	Content       string `form:"content" valid:"Required;MaxSize(65535)"`
-	ModifiedBy    string `form:"modified_by" valid:"Required;MaxSize(100)"`
 	CoverImageUrl string `form:"cover_image_url" valid:"Required;MaxSize(255)"`
 	State         int    `form:"state" valid:"Range(0,1)"`
 }
@@ -191,12 +233,11 @@ type EditArticleForm struct {
 // @Param title body string false "Title"
 // @Param desc body string false "Desc"
 // @Param content body string false "Content"
-// @Param modified_by body string true "ModifiedBy"
 // @Param state body int false "State"
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /api/v1/articles/{id} [put]
-func EditArticle(c *gin.Context) {
+func (h *Handler) EditArticle(c *gin.Context) {
 	var (
 		appG = app.Gin{C: c}
 		form = EditArticleForm{ID: com.StrTo(c.Param("id")).MustInt()}
@@ -208,14 +249,20 @@ func EditArticle(c *gin.Context) {
 		return
 	}
 
+	modifiedBy, ok := h.currentUsername(&appG)
+	if !ok {
+		return
+	}
+
 	articleService := article_service.Article{
+		Service:       h.Articles,
 		ID:            form.ID,
 		TagID:         form.TagID,
 		Title:         form.Title,
 		Desc:          form.Desc,
 		Content:       form.Content,
 		CoverImageUrl: form.CoverImageUrl,
-		ModifiedBy:    form.ModifiedBy,
+		ModifiedBy:    modifiedBy,
 		State:         form.State,
 	}
 	exists, err := articleService.ExistByID()
@@ -228,7 +275,17 @@ func EditArticle(c *gin.Context) {
 		return
 	}
 
-	tagService := tag_service.Tag{ID: form.TagID}
+	allowed, err := canEditArticle(c, &articleService, modifiedBy)
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, nil)
+		return
+	}
+	if !allowed {
+		appG.Response(http.StatusForbidden, e.ERROR_AUTH_PERMISSION, nil)
+		return
+	}
+
+	tagService := tag_service.Tag{Service: h.Tags, ID: form.TagID}
 	exists, err = tagService.ExistByID()
 	if err != nil {
 		appG.Response(http.StatusInternalServerError, e.ERROR_EXIST_TAG_FAIL, nil)
@@ -255,7 +312,7 @@ func EditArticle(c *gin.Context) {
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /api/v1/articles/{id} [delete]
-func DeleteArticle(c *gin.Context) {
+func (h *Handler) DeleteArticle(c *gin.Context) {
 	appG := app.Gin{C: c}
 	valid := validation.Validation{}
 	id := com.StrTo(c.Param("id")).MustInt()
@@ -263,11 +320,16 @@ func DeleteArticle(c *gin.Context) {
 
 	if valid.HasErrors() {
 		app.MarkErrors(valid.Errors)
-		appG.Response(http.StatusOK, e.INVALID_PARAMS, nil)
+		appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, nil)
+		return
+	}
+
+	username, ok := h.currentUsername(&appG)
+	if !ok {
 		return
 	}
 
-	articleService := article_service.Article{ID: id}
+	articleService := article_service.Article{Service: h.Articles, ID: id}
 	exists, err := articleService.ExistByID()
 	if err != nil {
 		appG.Response(http.StatusInternalServerError, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, nil)
@@ -278,6 +340,16 @@ func DeleteArticle(c *gin.Context) {
 		return
 	}
 
+	allowed, err := canEditArticle(c, &articleService, username)
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_CHECK_EXIST_ARTICLE_FAIL, nil)
+		return
+	}
+	if !allowed {
+		appG.Response(http.StatusForbidden, e.ERROR_AUTH_PERMISSION, nil)
+		return
+	}
+
 	err = articleService.Delete()
 	if err != nil {
 		appG.Response(http.StatusInternalServerError, e.ERROR_DELETE_ARTICLE_FAIL, nil)
@@ -287,13 +359,149 @@ func DeleteArticle(c *gin.Context) {
 	appG.Response(http.StatusOK, e.SUCCESS, nil)
 }
 
+// canEditArticle checks the current user may edit or delete the article,
+// a user with only permission.ARTICLE_EDIT_OWN must have created it
+func canEditArticle(c *gin.Context, articleService *article_service.Article, username string) (bool, error) {
+	if permission.Has(c, permission.ARTICLE_EDIT) {
+		return true, nil
+	}
+	if !permission.Has(c, permission.ARTICLE_EDIT_OWN) {
+		return false, nil
+	}
+
+	return articleService.IsCreatedBy(username)
+}
+
+// @Summary Export articles
+// @Produce  json
+// @Param tag_id body int false "TagID"
+// @Param state body int false "State"
+// @Param format body string false "xlsx, csv or jsonl"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /api/v1/articles/export [post]
+func (h *Handler) ExportArticle(c *gin.Context) {
+	appG := app.Gin{C: c}
+	valid := validation.Validation{}
+
+	state := -1
+	if arg := c.PostForm("state"); arg != "" {
+		state = com.StrTo(arg).MustInt()
+		valid.Range(state, 0, 1, "state")
+	}
+
+	tagId := -1
+	if arg := c.PostForm("tag_id"); arg != "" {
+		tagId = com.StrTo(arg).MustInt()
+		valid.Min(tagId, 1, "tag_id")
+	}
+
+	format := c.DefaultPostForm("format", export.FORMAT_XLSX)
+	if _, ok := export.GetFormatExt(format); !ok {
+		valid.SetError("format", "format must be xlsx, csv or jsonl")
+	}
+
+	if valid.HasErrors() {
+		app.MarkErrors(valid.Errors)
+		appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, nil)
+		return
+	}
+
+	articleService := article_service.Article{
+		Service: h.Articles,
+		TagID:   tagId,
+		State:   state,
+	}
+
+	filename, err := articleService.Export(format)
+	if err != nil {
+		appG.Logger().Warn(err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EXPORT_ARTICLE_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, map[string]string{
+		"export_url":      export.GetExcelFullUrl(&h.Config.App, filename),
+		"export_save_url": export.GetExcelPath(&h.Config.App) + filename,
+	})
+}
+
+// @Summary Import articles
+// @Produce  json
+// @Param file body file true "xlsx, CSV or JSON Lines File"
+// @Param format body string false "xlsx, csv or jsonl, defaults to the file extension"
+// @Param dry_run body bool false "DryRun"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /api/v1/articles/import [post]
+func (h *Handler) ImportArticle(c *gin.Context) {
+	appG := app.Gin{C: c}
+
+	dryRun := false
+	if arg := c.PostForm("dry_run"); arg != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(arg)
+		if err != nil {
+			appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, nil)
+			return
+		}
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		appG.Logger().Warn(err)
+		appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, nil)
+		return
+	}
+	defer file.Close()
+
+	format, ok := c.PostForm("format"), true
+	if format == "" {
+		format, ok = export.GetFormatByName(header.Filename)
+	} else {
+		_, ok = export.GetFormatExt(format)
+	}
+	if !ok {
+		appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, nil)
+		return
+	}
+
+	username, ok := h.currentUsername(&appG)
+	if !ok {
+		return
+	}
+
+	// the articles are created and modified by the importing user, only a user manager
+	// may keep the created_by and modified_by of the file, such as when restoring a backup
+	articleService := article_service.Article{
+		Service:     h.Articles,
+		CreatedBy:   username,
+		ModifiedBy:  username,
+		KeepAuthors: permission.Has(c, permission.USER_MANAGE),
+	}
+	report, err := articleService.Import(file, format, dryRun)
+	if err != nil {
+		appG.Logger().Warn(err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_IMPORT_ARTICLE_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, report)
+}
+
 const (
 	QRCODE_URL = "https://github.com/EDDYCJY/blog#gin%E7%B3%BB%E5%88%97%E7%9B%AE%E5%BD%95"
 )
 
-func GenerateArticlePoster(c *gin.Context) {
+func (h *Handler) GenerateArticlePoster(c *gin.Context) {
 	appG := app.Gin{C: c}
-	article := &article_service.Article{}
+	defer func() {
+		if code, ok := appG.Code(); ok {
+			metrics.CountPoster(code)
+		}
+	}()
+
+	article := &article_service.Article{Service: h.Articles}
 	qr := qrcode.NewQrCode(QRCODE_URL, 300, 300, qr.M, qr.Auto)
 	posterName := article_service.GetPosterFlag() + "-" + qrcode.GetQrCodeFileName(qr.URL) + qr.GetQrCodeExt()
 	articlePoster := article_service.NewArticlePoster(posterName, article, qr)
@@ -319,7 +527,7 @@ func GenerateArticlePoster(c *gin.Context) {
 	}
 
 	appG.Response(http.StatusOK, e.SUCCESS, map[string]string{
-		"poster_url":      qrcode.GetQrCodeFullUrl(posterName),
+		"poster_url":      qrcode.GetQrCodeFullUrl(&h.Config.App, posterName),
 		"poster_save_url": filePath + posterName,
 	})
 }
@@ -0,0 +1,12 @@
+package v1
+
+import "github.com/EGGYC/go-gin-example/pkg/container"
+
+// Handler serves the routes that need the services of App
+type Handler struct {
+	*container.App
+}
+
+func New(a *container.App) *Handler {
+	return &Handler{App: a}
+}
@@ -3,16 +3,16 @@ package v1
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/astaxie/beego/validation"
 	"github.com/gin-gonic/gin"
 	"github.com/unknwon/com"
 
+	"github.com/EGGYC/go-gin-example/middleware/permission"
 	"github.com/EGGYC/go-gin-example/pkg/app"
 	"github.com/EGGYC/go-gin-example/pkg/e"
 	"github.com/EGGYC/go-gin-example/pkg/export"
-	"github.com/EGGYC/go-gin-example/pkg/logging"
-	"github.com/EGGYC/go-gin-example/pkg/setting"
 	"github.com/EGGYC/go-gin-example/pkg/util"
 	"github.com/EGGYC/go-gin-example/service/tag_service"
 )
@@ -24,7 +24,7 @@ import (
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /api/v1/tags [get]
-func GetTags(c *gin.Context) {
+func (h *Handler) GetTags(c *gin.Context) {
 	appG := app.Gin{C: c}
 	name := c.Query("name")
 	state := -1
@@ -32,11 +32,13 @@ func GetTags(c *gin.Context) {
 		state = com.StrTo(arg).MustInt()
 	}
 
+	cfg := h.Settings()
 	tagService := tag_service.Tag{
+		Service:  h.Tags,
 		Name:     name,
 		State:    state,
-		PageNum:  util.GetPage(c),
-		PageSize: setting.AppSetting.PageSize,
+		PageNum:  util.GetPage(c, cfg.App.PageSize),
+		PageSize: cfg.App.PageSize,
 	}
 	tags, err := tagService.GetAll()
 	if err != nil {
@@ -57,20 +59,18 @@ func GetTags(c *gin.Context) {
 }
 
 type AddTagForm struct {
-	Name      string `form:"name" valid:"Required;MaxSize(100)"`
-	CreatedBy string `form:"created_by" valid:"Required;MaxSize(100)"`
-	State     int    `form:"state" valid:"Range(0,1)"`
+	Name  string `form:"name" valid:"Required;MaxSize(100)"`
+	State int    `form:"state" valid:"Range(0,1)"`
 }
 
 // @Summary Add article tag
 // @Produce  json
 // @Param name body string true "Name"
 // @Param state body int false "State"
-// @Param created_by body int false "CreatedBy"
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /api/v1/tags [post]
-func AddTag(c *gin.Context) {
+func (h *Handler) AddTag(c *gin.Context) {
 	var (
 		appG = app.Gin{C: c}
 		form AddTagForm
@@ -82,9 +82,15 @@ func AddTag(c *gin.Context) {
 		return
 	}
 
+	createdBy, ok := h.currentUsername(&appG)
+	if !ok {
+		return
+	}
+
 	tagService := tag_service.Tag{
+		Service:   h.Tags,
 		Name:      form.Name,
-		CreatedBy: form.CreatedBy,
+		CreatedBy: createdBy,
 		State:     form.State,
 	}
 	exists, err := tagService.ExistByName()
@@ -107,10 +113,9 @@ func AddTag(c *gin.Context) {
 }
 
 type EditTagForm struct {
-	ID         int    `form:"id" valid:"Required;Min(1)"`
-	Name       string `form:"name" valid:"Required;MaxSize(100)"`
-	ModifiedBy string `form:"modified_by" valid:"Required;MaxSize(100)"`
-	State      int    `form:"state" valid:"Range(0,1)"`
+	ID    int    `form:"id" valid:"Required;Min(1)"`
+	Name  string `form:"name" valid:"Required;MaxSize(100)"`
+	State int    `form:"state" valid:"Range(0,1)"`
 }
 
 // @Summary Update article tag
@@ -118,11 +123,10 @@ type EditTagForm struct {
 // @Param id path int true "ID"
 // @Param name body string true "Name"
 // @Param state body int false "State"
-// @Param modified_by body string true "ModifiedBy"
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /api/v1/tags/{id} [put]
-func EditTag(c *gin.Context) {
+func (h *Handler) EditTag(c *gin.Context) {
 	var (
 		appG = app.Gin{C: c}
 		form = EditTagForm{ID: com.StrTo(c.Param("id")).MustInt()}
@@ -134,10 +138,16 @@ func EditTag(c *gin.Context) {
 		return
 	}
 
+	modifiedBy, ok := h.currentUsername(&appG)
+	if !ok {
+		return
+	}
+
 	tagService := tag_service.Tag{
+		Service:    h.Tags,
 		ID:         form.ID,
 		Name:       form.Name,
-		ModifiedBy: form.ModifiedBy,
+		ModifiedBy: modifiedBy,
 		State:      form.State,
 	}
 
@@ -167,7 +177,7 @@ func EditTag(c *gin.Context) {
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /api/v1/tags/{id} [delete]
-func DeleteTag(c *gin.Context) {
+func (h *Handler) DeleteTag(c *gin.Context) {
 	appG := app.Gin{C: c}
 	valid := validation.Validation{}
 	id := com.StrTo(c.Param("id")).MustInt()
@@ -176,9 +186,10 @@ func DeleteTag(c *gin.Context) {
 	if valid.HasErrors() {
 		app.MarkErrors(valid.Errors)
 		appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, nil)
+		return
 	}
 
-	tagService := tag_service.Tag{ID: id}
+	tagService := tag_service.Tag{Service: h.Tags, ID: id}
 	exists, err := tagService.ExistByID()
 	if err != nil {
 		appG.Response(http.StatusInternalServerError, e.ERROR_EXIST_TAG_FAIL, nil)
@@ -205,7 +216,7 @@ func DeleteTag(c *gin.Context) {
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /api/v1/tags/export [post]
-func ExportTag(c *gin.Context) {
+func (h *Handler) ExportTag(c *gin.Context) {
 	appG := app.Gin{C: c}
 	name := c.PostForm("name")
 	state := -1
@@ -214,8 +225,9 @@ func ExportTag(c *gin.Context) {
 	}
 
 	tagService := tag_service.Tag{
-		Name:  name,
-		State: state,
+		Service: h.Tags,
+		Name:    name,
+		State:   state,
 	}
 
 	filename, err := tagService.Export()
@@ -225,36 +237,59 @@ func ExportTag(c *gin.Context) {
 	}
 
 	appG.Response(http.StatusOK, e.SUCCESS, map[string]string{
-		"export_url":      export.GetExcelFullUrl(filename),
-		"export_save_url": export.GetExcelPath() + filename,
+		"export_url":      export.GetExcelFullUrl(&h.Config.App, filename),
+		"export_save_url": export.GetExcelPath(&h.Config.App) + filename,
 	})
 }
 
 // @Summary Import article tag
 // @Produce  json
 // @Param file body file true "Excel File"
+// @Param dry_run body bool false "DryRun"
 // @Success 200 {object} app.Response
 // @Failure 500 {object} app.Response
 // @Router /api/v1/tags/import [post]
-func ImportTag(c *gin.Context) {
+func (h *Handler) ImportTag(c *gin.Context) {
 	appG := app.Gin{C: c}
 
+	dryRun := false
+	if arg := c.PostForm("dry_run"); arg != "" {
+		var err error
+		dryRun, err = strconv.ParseBool(arg)
+		if err != nil {
+			appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, nil)
+			return
+		}
+	}
+
 	file, _, err := c.Request.FormFile("file")
 	if err != nil {
-		logging.Warn(err)
-		appG.Response(http.StatusInternalServerError, e.ERROR, nil)
+		appG.Logger().Warn(err)
+		appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, nil)
+		return
+	}
+	defer file.Close()
+
+	createdBy, ok := h.currentUsername(&appG)
+	if !ok {
 		return
 	}
 
-	tagService := tag_service.Tag{}
-	err = tagService.Import(file)
+	// the tags are created by the importing user, only a user manager may keep the
+	// created_by of the file, such as when restoring a backup
+	tagService := tag_service.Tag{
+		Service:     h.Tags,
+		CreatedBy:   createdBy,
+		KeepAuthors: permission.Has(c, permission.USER_MANAGE),
+	}
+	report, err := tagService.Import(file, dryRun)
 	if err != nil {
-		logging.Warn(err)
+		appG.Logger().Warn(err)
 		appG.Response(http.StatusInternalServerError, e.ERROR_IMPORT_TAG_FAIL, nil)
 		return
 	}
 
-	appG.Response(http.StatusOK, e.SUCCESS, nil)
+	appG.Response(http.StatusOK, e.SUCCESS, report)
 }
 
 //
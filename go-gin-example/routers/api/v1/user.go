@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/unknwon/com"
+
+	"github.com/EGGYC/go-gin-example/middleware/jwt"
+	"github.com/EGGYC/go-gin-example/models"
+	"github.com/EGGYC/go-gin-example/pkg/app"
+	"github.com/EGGYC/go-gin-example/pkg/e"
+	"github.com/EGGYC/go-gin-example/service/auth_service"
+)
+
+type EditUserRoleForm struct {
+	ID   int    `form:"id" valid:"Required;Min(1)"`
+	Role string `form:"role" valid:"Required;MaxSize(20)"`
+}
+
+// @Summary Change the role of a user
+// @Produce  json
+// @Param id path int true "ID"
+// @Param role body string true "admin, editor, author or reader"
+// @Success 200 {object} app.Response
+// @Failure 500 {object} app.Response
+// @Router /api/v1/users/{id}/role [put]
+func (h *Handler) EditUserRole(c *gin.Context) {
+	var (
+		appG = app.Gin{C: c}
+		form = EditUserRoleForm{ID: com.StrTo(c.Param("id")).MustInt()}
+	)
+
+	httpCode, errCode := app.BindAndValid(c, &form)
+	if errCode != e.SUCCESS {
+		appG.Response(httpCode, errCode, nil)
+		return
+	}
+	if !models.IsRole(form.Role) {
+		appG.Response(http.StatusBadRequest, e.INVALID_PARAMS, nil)
+		return
+	}
+
+	authService := auth_service.Auth{Service: h.Auth, ID: form.ID, Role: form.Role}
+	auth, err := authService.Get()
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_EXIST_AUTH_FAIL, nil)
+		return
+	}
+	if auth == nil {
+		appG.Response(http.StatusOK, e.ERROR_NOT_EXIST_AUTH, nil)
+		return
+	}
+
+	// an admin may demote themselves, unless nobody would be left to manage the users
+	err = authService.EditRole()
+	if err == models.ErrLastAdmin {
+		appG.Response(http.StatusOK, e.ERROR_LAST_ADMIN, nil)
+		return
+	}
+	if err != nil {
+		appG.Logger().Warn(err)
+		appG.Response(http.StatusInternalServerError, e.ERROR_EDIT_AUTH_ROLE_FAIL, nil)
+		return
+	}
+
+	appG.Response(http.StatusOK, e.SUCCESS, nil)
+}
+
+// currentUsername returns the username of the account the token belongs to, it is
+// the identity recorded in created_by and modified_by. When ok is false the
+// request has already been answered
+func (h *Handler) currentUsername(appG *app.Gin) (username string, ok bool) {
+	authService := auth_service.Auth{Service: h.Auth, ID: jwt.GetClaims(appG.C).UserID}
+	auth, err := authService.Get()
+	if err != nil {
+		appG.Response(http.StatusInternalServerError, e.ERROR_EXIST_AUTH_FAIL, nil)
+		return "", false
+	}
+	if auth == nil {
+		appG.Response(http.StatusUnauthorized, e.ERROR_NOT_EXIST_AUTH, nil)
+		return "", false
+	}
+
+	return auth.Username, true
+}
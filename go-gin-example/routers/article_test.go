@@ -0,0 +1,433 @@
+package routers_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/EGGYC/go-gin-example/models"
+	"github.com/EGGYC/go-gin-example/pkg/e"
+	"github.com/EGGYC/go-gin-example/pkg/importer"
+)
+
+// articleForm is a valid form of POST /api/v1/articles and PUT /api/v1/articles/:id
+func articleForm(tagID int, title string) url.Values {
+	return url.Values{
+		"tag_id":          {strconv.Itoa(tagID)},
+		"title":           {title},
+		"desc":            {title + " desc"},
+		"content":         {title + " content"},
+		"cover_image_url": {"http://127.0.0.1:8000/upload/images/cover.jpg"},
+		"state":           {"1"},
+	}
+}
+
+func articlePath(id int) string {
+	return "/api/v1/articles/" + strconv.Itoa(id)
+}
+
+// getArticle returns the article id through GET /api/v1/articles/:id
+func (h *harness) getArticle(token string, id int) *models.Article {
+	h.t.Helper()
+
+	var article models.Article
+	h.expect(h.do(http.MethodGet, articlePath(id), token, nil), http.StatusOK, e.SUCCESS).decode(h.t, &article)
+
+	return &article
+}
+
+func TestAuthRequired(t *testing.T) {
+	h := newHarness(t)
+
+	h.expect(h.do(http.MethodGet, "/api/v1/articles", "", nil), http.StatusUnauthorized, e.INVALID_PARAMS)
+	h.expect(h.do(http.MethodGet, "/api/v1/tags", "not-a-token", nil), http.StatusUnauthorized, e.ERROR_AUTH_CHECK_TOKEN_FAIL)
+	h.expect(h.do(http.MethodPost, "/auth", "", url.Values{"username": {readerUser.Username}, "password": {"wrong"}}),
+		http.StatusUnauthorized, e.ERROR_AUTH)
+
+	reader := h.login(readerUser)
+	h.expect(h.do(http.MethodPost, "/api/v1/tags", reader, url.Values{"name": {"gin"}}), http.StatusForbidden, e.ERROR_AUTH_PERMISSION)
+	h.expect(h.do(http.MethodPost, "/api/v1/articles", reader, articleForm(h.fixtures.GoTag, "Mine")),
+		http.StatusForbidden, e.ERROR_AUTH_PERMISSION)
+	h.expect(h.do(http.MethodDelete, articlePath(h.fixtures.Published), reader, nil), http.StatusForbidden, e.ERROR_AUTH_PERMISSION)
+}
+
+func TestGetArticle(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(readerUser)
+
+	article := h.getArticle(token, h.fixtures.Published)
+	if article.Title != "Published" || article.Tag.Name != "go" || article.CreatedBy != authorUser.Username {
+		t.Fatalf("got %q of tag %q by %q, want Published of go by %s", article.Title, article.Tag.Name, article.CreatedBy, authorUser.Username)
+	}
+
+	h.expect(h.do(http.MethodGet, articlePath(9999), token, nil), http.StatusOK, e.ERROR_NOT_EXIST_ARTICLE)
+	h.expect(h.do(http.MethodGet, articlePath(0), token, nil), http.StatusBadRequest, e.INVALID_PARAMS)
+}
+
+func TestGetArticleFail(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(readerUser)
+
+	h.dropTable("tag")
+	h.expect(h.do(http.MethodGet, articlePath(h.fixtures.Published), token, nil), http.StatusInternalServerError, e.ERROR_GET_ARTICLE_FAIL)
+}
+
+func TestGetArticles(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(readerUser)
+
+	var articles list
+	h.expect(h.do(http.MethodGet, "/api/v1/articles", token, nil), http.StatusOK, e.SUCCESS).decode(t, &articles)
+	if articles.Total != 2 || len(articles.Lists) != 2 {
+		t.Fatalf("got %d articles of %d, want 2 of 2", len(articles.Lists), articles.Total)
+	}
+}
+
+func TestGetArticlesFail(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(readerUser)
+
+	// the list preloads the tags, counting doesn't
+	h.dropTable("tag")
+	h.expect(h.do(http.MethodGet, "/api/v1/articles", token, nil), http.StatusInternalServerError, e.ERROR_GET_ARTICLES_FAIL)
+
+	h.dropTable("article")
+	h.expect(h.do(http.MethodGet, "/api/v1/articles", token, nil), http.StatusInternalServerError, e.ERROR_COUNT_ARTICLE_FAIL)
+}
+
+func TestAddArticle(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(authorUser)
+
+	// the cached list is cleared by the new article
+	h.expect(h.do(http.MethodGet, "/api/v1/articles", token, nil), http.StatusOK, e.SUCCESS)
+	h.expect(h.do(http.MethodPost, "/api/v1/articles", token, articleForm(h.fixtures.GoTag, "New")), http.StatusOK, e.SUCCESS)
+
+	var articles list
+	h.expect(h.do(http.MethodGet, "/api/v1/articles", token, nil), http.StatusOK, e.SUCCESS).decode(t, &articles)
+	if articles.Total != 3 || len(articles.Lists) != 3 {
+		t.Fatalf("got %d articles of %d, want 3 of 3", len(articles.Lists), articles.Total)
+	}
+
+	h.expect(h.do(http.MethodPost, "/api/v1/articles", token, articleForm(9999, "Orphan")), http.StatusOK, e.ERROR_NOT_EXIST_TAG)
+
+	form := articleForm(h.fixtures.GoTag, "No cover")
+	form.Del("cover_image_url")
+	h.expect(h.do(http.MethodPost, "/api/v1/articles", token, form), http.StatusBadRequest, e.INVALID_PARAMS)
+	form = articleForm(h.fixtures.GoTag, strings.Repeat("x", 101))
+	h.expect(h.do(http.MethodPost, "/api/v1/articles", token, form), http.StatusBadRequest, e.INVALID_PARAMS)
+}
+
+func TestAddArticleFail(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(authorUser)
+
+	h.failOn("article", "INSERT")
+	h.expect(h.do(http.MethodPost, "/api/v1/articles", token, articleForm(h.fixtures.GoTag, "New")),
+		http.StatusInternalServerError, e.ERROR_ADD_ARTICLE_FAIL)
+
+	h.dropTable("tag")
+	h.expect(h.do(http.MethodPost, "/api/v1/articles", token, articleForm(h.fixtures.GoTag, "New")),
+		http.StatusInternalServerError, e.ERROR_EXIST_TAG_FAIL)
+}
+
+func TestEditArticle(t *testing.T) {
+	h := newHarness(t)
+	author := h.login(authorUser)
+	path := articlePath(h.fixtures.Published)
+
+	// the cached article is cleared by the edit
+	h.getArticle(author, h.fixtures.Published)
+	h.expect(h.do(http.MethodPut, path, author, articleForm(h.fixtures.EmptyTag, "Edited")), http.StatusOK, e.SUCCESS)
+
+	article := h.getArticle(author, h.fixtures.Published)
+	if article.Title != "Edited" || article.TagID != h.fixtures.EmptyTag || article.ModifiedBy != authorUser.Username {
+		t.Fatalf("got %q of tag %d modified by %q, want Edited of tag %d by %s",
+			article.Title, article.TagID, article.ModifiedBy, h.fixtures.EmptyTag, authorUser.Username)
+	}
+
+	h.expect(h.do(http.MethodPut, articlePath(9999), author, articleForm(h.fixtures.GoTag, "Missing")), http.StatusOK, e.ERROR_NOT_EXIST_ARTICLE)
+	h.expect(h.do(http.MethodPut, path, author, articleForm(9999, "Orphan")), http.StatusOK, e.ERROR_NOT_EXIST_TAG)
+	h.expect(h.do(http.MethodPut, articlePath(0), author, articleForm(h.fixtures.GoTag, "Zero")), http.StatusBadRequest, e.INVALID_PARAMS)
+
+	// an author only edits their own articles, an admin edits any
+	admin := h.login(adminUser)
+	h.expect(h.do(http.MethodPost, "/api/v1/articles", admin, articleForm(h.fixtures.GoTag, "Admin's")), http.StatusOK, e.SUCCESS)
+	adminArticle := h.lookupID("article", "title", "Admin's")
+	h.expect(h.do(http.MethodPut, articlePath(adminArticle), author, articleForm(h.fixtures.GoTag, "Taken")),
+		http.StatusForbidden, e.ERROR_AUTH_PERMISSION)
+	h.expect(h.do(http.MethodPut, path, admin, articleForm(h.fixtures.GoTag, "Reviewed")), http.StatusOK, e.SUCCESS)
+}
+
+func TestEditArticleFail(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(authorUser)
+	path := articlePath(h.fixtures.Published)
+
+	h.failOn("article", "UPDATE")
+	h.expect(h.do(http.MethodPut, path, token, articleForm(h.fixtures.GoTag, "Edited")), http.StatusInternalServerError, e.ERROR_EDIT_ARTICLE_FAIL)
+
+	h.dropTable("article")
+	h.expect(h.do(http.MethodPut, path, token, articleForm(h.fixtures.GoTag, "Edited")), http.StatusInternalServerError, e.ERROR_CHECK_EXIST_ARTICLE_FAIL)
+}
+
+func TestDeleteArticle(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(authorUser)
+	path := articlePath(h.fixtures.Draft)
+
+	h.getArticle(token, h.fixtures.Draft)
+	h.expect(h.do(http.MethodDelete, path, token, nil), http.StatusOK, e.SUCCESS)
+	h.expect(h.do(http.MethodGet, path, token, nil), http.StatusOK, e.ERROR_NOT_EXIST_ARTICLE)
+	h.expect(h.do(http.MethodDelete, path, token, nil), http.StatusOK, e.ERROR_NOT_EXIST_ARTICLE)
+	h.expect(h.do(http.MethodDelete, articlePath(0), token, nil), http.StatusBadRequest, e.INVALID_PARAMS)
+}
+
+func TestDeleteArticleFail(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(authorUser)
+	path := articlePath(h.fixtures.Draft)
+
+	h.failOn("article", "UPDATE")
+	h.expect(h.do(http.MethodDelete, path, token, nil), http.StatusInternalServerError, e.ERROR_DELETE_ARTICLE_FAIL)
+
+	h.dropTable("article")
+	h.expect(h.do(http.MethodDelete, path, token, nil), http.StatusInternalServerError, e.ERROR_CHECK_EXIST_ARTICLE_FAIL)
+}
+
+func TestExportArticle(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(readerUser)
+
+	var data map[string]string
+	h.expect(h.do(http.MethodPost, "/api/v1/articles/export", token, url.Values{"format": {"jsonl"}, "state": {"1"}}),
+		http.StatusOK, e.SUCCESS).decode(t, &data)
+
+	f, err := os.Open(filepath.Join(h.app.Config.App.RuntimeRootPath, data["export_save_url"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var titles []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var article struct {
+			Title string `json:"title"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &article); err != nil {
+			t.Fatal(err)
+		}
+		titles = append(titles, article.Title)
+	}
+	if len(titles) != 1 || titles[0] != "Published" {
+		t.Fatalf("got %v, want [Published]", titles)
+	}
+
+	h.expect(h.do(http.MethodPost, "/api/v1/articles/export", token, url.Values{"format": {"pdf"}}), http.StatusBadRequest, e.INVALID_PARAMS)
+}
+
+func TestExportArticleFail(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(readerUser)
+
+	h.dropTable("article")
+	h.expect(h.do(http.MethodPost, "/api/v1/articles/export", token, nil), http.StatusInternalServerError, e.ERROR_EXPORT_ARTICLE_FAIL)
+}
+
+func TestImportArticle(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(adminUser)
+	content := articleLines(t,
+		map[string]interface{}{"id": h.fixtures.Published, "tag_id": h.fixtures.GoTag, "title": "Imported", "desc": "d",
+			"content": "c", "cover_image_url": "cover.jpg", "state": 1, "created_by": authorUser.Username},
+		map[string]interface{}{"tag_id": h.fixtures.GoTag, "title": "Brand new", "desc": "d",
+			"content": "c", "cover_image_url": "cover.jpg", "state": 1, "created_by": adminUser.Username},
+		map[string]interface{}{"tag_id": 9999, "title": "Orphan", "desc": "d",
+			"content": "c", "cover_image_url": "cover.jpg", "state": 1, "created_by": adminUser.Username},
+	)
+
+	var report importer.Report
+	h.expect(h.upload("/api/v1/articles/import", token, "articles.jsonl", content, url.Values{"dry_run": {"1"}}),
+		http.StatusOK, e.SUCCESS).decode(t, &report)
+	if !report.DryRun || report.Created != 1 || report.Updated != 1 || report.Invalid != 1 {
+		t.Fatalf("dry run: got %+v, want 1 created, 1 updated and 1 invalid", report)
+	}
+	if article := h.getArticle(token, h.fixtures.Published); article.Title != "Published" {
+		t.Fatalf("dry run changed the title to %q", article.Title)
+	}
+
+	h.expect(h.upload("/api/v1/articles/import", token, "articles.jsonl", content, nil), http.StatusOK, e.SUCCESS).decode(t, &report)
+	if report.DryRun || report.Created != 1 || report.Updated != 1 {
+		t.Fatalf("got %+v, want 1 created and 1 updated", report)
+	}
+	if article := h.getArticle(token, h.fixtures.Published); article.Title != "Imported" || article.ModifiedBy != adminUser.Username {
+		t.Fatalf("got %q modified by %q, want Imported by %s", article.Title, article.ModifiedBy, adminUser.Username)
+	}
+
+	h.expect(h.upload("/api/v1/articles/import", token, "articles.pdf", content, nil), http.StatusBadRequest, e.INVALID_PARAMS)
+	h.expect(h.do(http.MethodPost, "/api/v1/articles/import", token, url.Values{}), http.StatusBadRequest, e.INVALID_PARAMS)
+
+	author := h.login(authorUser)
+	h.expect(h.upload("/api/v1/articles/import", author, "articles.jsonl", content, nil), http.StatusForbidden, e.ERROR_AUTH_PERMISSION)
+}
+
+func TestImportArticleAuthors(t *testing.T) {
+	h := newHarness(t)
+
+	// only a user manager keeps the created_by and modified_by of the file
+	for _, tt := range []struct {
+		user       user
+		modifiedBy string
+		createdBy  string
+	}{
+		{editorUser, editorUser.Username, editorUser.Username},
+		{adminUser, "someone", "someone"},
+	} {
+		token := h.login(tt.user)
+		content := articleLines(t,
+			map[string]interface{}{"id": h.fixtures.Published, "tag_id": h.fixtures.GoTag, "title": "Imported", "desc": "d",
+				"content": "c", "cover_image_url": "cover.jpg", "state": 1, "created_by": "someone", "modified_by": "someone"},
+			map[string]interface{}{"tag_id": h.fixtures.GoTag, "title": "Brand new", "desc": "d",
+				"content": "c", "cover_image_url": "cover.jpg", "state": 1, "created_by": "someone"},
+		)
+		var report importer.Report
+		h.expect(h.upload("/api/v1/articles/import", token, "articles.jsonl", content, nil), http.StatusOK, e.SUCCESS).decode(t, &report)
+
+		if article := h.getArticle(token, h.fixtures.Published); article.ModifiedBy != tt.modifiedBy || article.CreatedBy != authorUser.Username {
+			t.Errorf("updated by %s: got created by %q modified by %q, want %s and %s",
+				tt.user.Username, article.CreatedBy, article.ModifiedBy, authorUser.Username, tt.modifiedBy)
+		}
+		if article := h.getArticle(token, report.Rows[1].ID); article.CreatedBy != tt.createdBy {
+			t.Errorf("created by %s: got created by %q, want %s", tt.user.Username, article.CreatedBy, tt.createdBy)
+		}
+	}
+}
+
+func TestImportDeletedArticle(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(adminUser)
+	h.expect(h.do(http.MethodDelete, articlePath(h.fixtures.Draft), token, nil), http.StatusOK, e.SUCCESS)
+
+	content := articleLines(t,
+		map[string]interface{}{"id": h.fixtures.Draft, "tag_id": h.fixtures.GoTag, "title": "Back", "desc": "d",
+			"content": "c", "cover_image_url": "cover.jpg", "state": 1, "created_by": authorUser.Username},
+		map[string]interface{}{"tag_id": h.fixtures.GoTag, "title": "Brand new", "desc": "d",
+			"content": "c", "cover_image_url": "cover.jpg", "state": 1, "created_by": adminUser.Username},
+	)
+	var report importer.Report
+	h.expect(h.upload("/api/v1/articles/import", token, "articles.jsonl", content, nil), http.StatusOK, e.SUCCESS).decode(t, &report)
+	if report.Created != 1 || report.Invalid != 1 || report.Rows[0].Result != importer.RESULT_INVALID {
+		t.Fatalf("got %+v, want the deleted article invalid and 1 created", report)
+	}
+	h.expect(h.do(http.MethodGet, articlePath(h.fixtures.Draft), token, nil), http.StatusOK, e.ERROR_NOT_EXIST_ARTICLE)
+}
+
+func TestImportArticleFail(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(adminUser)
+
+	h.failOn("article", "INSERT")
+	content := articleLines(t, map[string]interface{}{"tag_id": h.fixtures.GoTag, "title": "Brand new", "desc": "d",
+		"content": "c", "cover_image_url": "cover.jpg", "state": 1, "created_by": adminUser.Username})
+	h.expect(h.upload("/api/v1/articles/import", token, "articles.jsonl", content, nil),
+		http.StatusInternalServerError, e.ERROR_IMPORT_ARTICLE_FAIL)
+}
+
+func TestSearchArticles(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(adminUser)
+	search := func(query string) *list {
+		t.Helper()
+
+		var results list
+		h.expect(h.do(http.MethodGet, "/api/v1/articles/search?"+query, token, nil), http.StatusOK, e.SUCCESS).decode(t, &results)
+		return &results
+	}
+
+	results := search("q=published")
+	if titles := results.values("title"); results.Total != 1 || len(titles) != 1 || titles[0] != "Published" {
+		t.Fatalf("published: got %v of %d, want [Published] of 1", titles, results.Total)
+	}
+	highlights := results.Lists[0]["highlights"].(map[string]interface{})
+	if highlights["title"] != "<em>Published</em>" || highlights["content"] != "<em>Published</em> content" {
+		t.Errorf("published: got highlights %v", highlights)
+	}
+
+	// a title match ranks above a content match
+	form := articleForm(h.fixtures.GoTag, "Gin routing")
+	form.Set("content", "Groups of routes")
+	h.expect(h.do(http.MethodPost, "/api/v1/articles", token, form), http.StatusOK, e.SUCCESS)
+	form = articleForm(h.fixtures.GoTag, "Middleware")
+	form.Set("content", "Every gin handler goes through it")
+	h.expect(h.do(http.MethodPost, "/api/v1/articles", token, form), http.StatusOK, e.SUCCESS)
+	results = search("q=gin")
+	if titles := results.values("title"); results.Total != 2 || !reflect.DeepEqual(titles, []string{"Gin routing", "Middleware"}) {
+		t.Fatalf("gin: got %v of %d, want [Gin routing Middleware] of 2", titles, results.Total)
+	}
+	results = search("q=gin&page=2")
+	if results.Total != 2 || len(results.Lists) != 0 {
+		t.Fatalf("gin page 2: got %d of %d, want 0 of 2", len(results.Lists), results.Total)
+	}
+
+	routing := h.lookupID("article", "title", "Gin routing")
+	h.expect(h.do(http.MethodPut, articlePath(routing), token, articleForm(h.fixtures.GoTag, "Echo routing")), http.StatusOK, e.SUCCESS)
+	if titles := search("q=gin").values("title"); !reflect.DeepEqual(titles, []string{"Middleware"}) {
+		t.Fatalf("gin after the edit: got %v, want [Middleware]", titles)
+	}
+	h.expect(h.do(http.MethodDelete, articlePath(routing), token, nil), http.StatusOK, e.SUCCESS)
+	if results := search("q=echo"); results.Total != 0 {
+		t.Fatalf("echo after the delete: got %v, want none", results.values("title"))
+	}
+
+	content := articleLines(t, map[string]interface{}{"tag_id": h.fixtures.GoTag, "title": "Imported 全文检索", "desc": "d",
+		"content": "c", "cover_image_url": "cover.jpg", "state": 1, "created_by": adminUser.Username})
+	h.expect(h.upload("/api/v1/articles/import", token, "articles.jsonl", content, nil), http.StatusOK, e.SUCCESS)
+	if titles := search("q=" + url.QueryEscape("检索")).values("title"); !reflect.DeepEqual(titles, []string{"Imported 全文检索"}) {
+		t.Fatalf("检索: got %v, want [Imported 全文检索]", titles)
+	}
+
+	h.expect(h.do(http.MethodGet, "/api/v1/articles/search?q=+", token, nil), http.StatusBadRequest, e.INVALID_PARAMS)
+	h.expect(h.do(http.MethodGet, "/api/v1/articles/search", token, nil), http.StatusBadRequest, e.INVALID_PARAMS)
+}
+
+func TestSearchArticlesFail(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(readerUser)
+
+	h.dropTable("article")
+	h.expect(h.do(http.MethodGet, "/api/v1/articles/search?q=published", token, nil),
+		http.StatusInternalServerError, e.ERROR_SEARCH_ARTICLES_FAIL)
+}
+
+func TestGenerateArticlePosterFail(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(readerUser)
+
+	// the runtime of the harness has no bg.jpg to draw the poster on
+	h.expect(h.do(http.MethodPost, "/api/v1/articles/poster/generate", token, nil),
+		http.StatusInternalServerError, e.ERROR_GEN_ARTICLE_POSTER_FAIL)
+}
+
+// articleLines writes records in the jsonl format of POST /api/v1/articles/import
+func articleLines(t *testing.T, records ...map[string]interface{}) []byte {
+	t.Helper()
+
+	var lines []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+
+	return lines
+}
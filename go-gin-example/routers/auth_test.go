@@ -0,0 +1,65 @@
+package routers_test
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/EGGYC/go-gin-example/pkg/e"
+	"github.com/EGGYC/go-gin-example/pkg/util"
+)
+
+func TestRefreshToken(t *testing.T) {
+	h := newHarness(t)
+
+	var tokens util.TokenPair
+	h.expect(h.do(http.MethodPost, "/auth", "", url.Values{"username": {readerUser.Username}, "password": {readerUser.Password}}),
+		http.StatusOK, e.SUCCESS).decode(t, &tokens)
+	form := url.Values{"refresh_token": {tokens.RefreshToken}}
+
+	// of concurrent refreshes with the same token only one gets a new pair
+	codes := make([]int, 10)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = h.do(http.MethodPost, "/auth/refresh", "", form).Code
+		}(i)
+	}
+	wg.Wait()
+
+	ok := 0
+	for _, code := range codes {
+		if code == http.StatusOK {
+			ok++
+		}
+	}
+	if ok != 1 {
+		t.Fatalf("got HTTP %v, want a single 200", codes)
+	}
+
+	h.expect(h.do(http.MethodPost, "/auth/refresh", "", form), http.StatusUnauthorized, e.ERROR_AUTH_TOKEN_REVOKED)
+	h.expect(h.do(http.MethodPost, "/auth/refresh", "", url.Values{"refresh_token": {tokens.AccessToken}}),
+		http.StatusUnauthorized, e.ERROR_AUTH_CHECK_TOKEN_FAIL)
+}
+
+func TestChangePasswordRevokesTokens(t *testing.T) {
+	h := newHarness(t)
+
+	var old util.TokenPair
+	h.expect(h.do(http.MethodPost, "/auth", "", url.Values{"username": {readerUser.Username}, "password": {readerUser.Password}}),
+		http.StatusOK, e.SUCCESS).decode(t, &old)
+
+	h.expect(h.do(http.MethodPut, "/auth/password", old.AccessToken, url.Values{"old_password": {readerUser.Password}, "new_password": {"new-reader-pass"}}),
+		http.StatusOK, e.SUCCESS)
+
+	// the tokens issued before the change are refused, both for the API and for a refresh
+	h.expect(h.do(http.MethodGet, "/api/v1/tags", old.AccessToken, nil), http.StatusUnauthorized, e.ERROR_AUTH_TOKEN_REVOKED)
+	h.expect(h.do(http.MethodPost, "/auth/refresh", "", url.Values{"refresh_token": {old.RefreshToken}}),
+		http.StatusUnauthorized, e.ERROR_AUTH_TOKEN_REVOKED)
+
+	token := h.login(user{Username: readerUser.Username, Password: "new-reader-pass"})
+	h.expect(h.do(http.MethodGet, "/api/v1/tags", token, nil), http.StatusOK, e.SUCCESS)
+}
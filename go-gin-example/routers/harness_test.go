@@ -0,0 +1,325 @@
+package routers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+
+	"github.com/EGGYC/go-gin-example/models"
+	"github.com/EGGYC/go-gin-example/pkg/container"
+	"github.com/EGGYC/go-gin-example/pkg/e"
+	"github.com/EGGYC/go-gin-example/pkg/lifecycle"
+	"github.com/EGGYC/go-gin-example/pkg/logging"
+	"github.com/EGGYC/go-gin-example/pkg/setting"
+	"github.com/EGGYC/go-gin-example/pkg/util"
+	"github.com/EGGYC/go-gin-example/routers"
+	"github.com/EGGYC/go-gin-example/service/article_service"
+)
+
+// user is an account of the fixtures
+type user struct {
+	Username string
+	Password string
+	Role     string
+}
+
+// the fixtures add an account of every role, the seeded test account is a reader
+var (
+	adminUser  = user{Username: "admin", Password: "admin-pass", Role: models.ROLE_ADMIN}
+	editorUser = user{Username: "editor", Password: "editor-pass", Role: models.ROLE_EDITOR}
+	authorUser = user{Username: "author", Password: "author-pass", Role: models.ROLE_AUTHOR}
+	readerUser = user{Username: "reader", Password: "reader-pass", Role: models.ROLE_READER}
+)
+
+// fixtures are the rows every harness starts with
+type fixtures struct {
+	// GoTag is enabled and has both articles, EmptyTag is disabled and has none
+	GoTag    int
+	EmptyTag int
+	// Published is enabled and Draft is disabled, both are written by authorUser
+	Published int
+	Draft     int
+}
+
+// harness serves routers.InitRouter on an in-memory SQLite database and an
+// in-memory cache, Redis is left unreachable so the denylist stays in memory
+type harness struct {
+	t        *testing.T
+	app      *container.App
+	handler  http.Handler
+	fixtures fixtures
+}
+
+// response is the body written by app.Gin.Response
+type response struct {
+	Code int             `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// newHarness builds a fresh App per test, settings are global so tests using it
+// must not run in parallel
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+
+	runtime := t.TempDir()
+	if err := os.Mkdir(filepath.Join(runtime, "fonts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	err := setting.Setup(&setting.Options{
+		ConfigFile: "../conf/app.ini",
+		Overrides: []string{
+			"app.JwtSecret=harness-secret-0123456789",
+			"app.RuntimeRootPath=" + runtime + "/",
+			"server.RunMode=test",
+			"database.Type=sqlite3",
+			"database.Name=:memory:",
+			"cache.Type=memory",
+			"redis.Host=",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lc := &lifecycle.Manager{}
+	t.Cleanup(func() {
+		if err := lc.Shutdown(context.Background()); err != nil {
+			t.Error(err)
+		}
+	})
+	a, err := container.New(setting.Current(), lc, &container.Options{
+		Logger: logging.New(testWriter{t}, logging.WARNING, logging.FORMAT_TEXT),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &harness{t: t, app: a, handler: routers.InitRouter(a)}
+	h.seed()
+
+	return h
+}
+
+// testWriter sends the application log to the test output
+type testWriter struct {
+	t *testing.T
+}
+
+func (w testWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
+func (h *harness) seed() {
+	h.t.Helper()
+	db := h.app.DB
+
+	for _, u := range []user{adminUser, editorUser, authorUser, readerUser} {
+		hashed, err := util.HashPassword(u.Password)
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		if err := db.AddAuth(u.Username, hashed, u.Role); err != nil {
+			h.t.Fatal(err)
+		}
+	}
+
+	if err := db.AddTag("go", 1, adminUser.Username); err != nil {
+		h.t.Fatal(err)
+	}
+	if err := db.AddTag("empty", 0, adminUser.Username); err != nil {
+		h.t.Fatal(err)
+	}
+	h.fixtures.GoTag = h.lookupID("tag", "name", "go")
+	h.fixtures.EmptyTag = h.lookupID("tag", "name", "empty")
+
+	for _, article := range []struct {
+		id    *int
+		title string
+		state int
+	}{
+		{&h.fixtures.Published, "Published", 1},
+		{&h.fixtures.Draft, "Draft", 0},
+	} {
+		// added through the service so the search index has them too
+		articleService := article_service.Article{
+			Service:       h.app.Articles,
+			TagID:         h.fixtures.GoTag,
+			Title:         article.title,
+			Desc:          article.title + " desc",
+			Content:       article.title + " content",
+			CoverImageUrl: "http://127.0.0.1:8000/upload/images/cover.jpg",
+			State:         article.state,
+			CreatedBy:     authorUser.Username,
+		}
+		if err := articleService.Add(); err != nil {
+			h.t.Fatal(err)
+		}
+		*article.id = articleService.ID
+	}
+}
+
+// table returns the name of a table with the [database] TablePrefix
+func (h *harness) table(name string) string {
+	return h.app.Config.Database.TablePrefix + name
+}
+
+// exec runs statements on the database of the harness, such as the ones breaking a table
+func (h *harness) exec(format string, args ...interface{}) {
+	h.t.Helper()
+
+	sql := fmt.Sprintf(format, args...)
+	err := h.app.DB.Transaction(func(tx *gorm.DB) error {
+		return tx.Exec(sql).Error
+	})
+	if err != nil {
+		h.t.Fatalf("exec %q: %v", sql, err)
+	}
+}
+
+func (h *harness) lookupID(table, column, value string) int {
+	h.t.Helper()
+
+	var id int
+	err := h.app.DB.Transaction(func(tx *gorm.DB) error {
+		return tx.Table(h.table(table)).Where(column+" = ?", value).Select("id").Row().Scan(&id)
+	})
+	if err != nil {
+		h.t.Fatalf("lookup %s %s=%s: %v", table, column, value, err)
+	}
+
+	return id
+}
+
+// dropTable renames a table so every query on it fails, the rename keeps SQLite
+// from failing the statements that only read the other tables
+func (h *harness) dropTable(name string) {
+	h.exec("ALTER TABLE %s RENAME TO %s_dropped", h.table(name), h.table(name))
+}
+
+// failOn makes every INSERT or UPDATE on a table fail, a soft delete is an UPDATE
+func (h *harness) failOn(name, op string) {
+	h.exec("CREATE TRIGGER fail_%s_%s BEFORE %s ON %s BEGIN SELECT RAISE(ABORT, 'injected failure'); END",
+		name, strings.ToLower(op), op, h.table(name))
+}
+
+// login returns an access token of u from POST /auth
+func (h *harness) login(u user) string {
+	h.t.Helper()
+
+	rec := h.do(http.MethodPost, "/auth", "", url.Values{"username": {u.Username}, "password": {u.Password}})
+	var tokens util.TokenPair
+	h.expect(rec, http.StatusOK, e.SUCCESS).decode(h.t, &tokens)
+	if tokens.AccessToken == "" {
+		h.t.Fatalf("login %s: no token in %s", u.Username, rec.Body)
+	}
+
+	return tokens.AccessToken
+}
+
+// do sends form url-encoded, a query string belongs to path. token is sent as a
+// Bearer token unless empty
+func (h *harness) do(method, path, token string, form url.Values) *httptest.ResponseRecorder {
+	var req *http.Request
+	if form != nil {
+		req = httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req = httptest.NewRequest(method, path, nil)
+	}
+
+	return h.serve(req, token)
+}
+
+// upload posts content as the file field named filename, with the other fields of form
+func (h *harness) upload(path, token, filename string, content []byte, form url.Values) *httptest.ResponseRecorder {
+	h.t.Helper()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for key, values := range form {
+		for _, value := range values {
+			if err := w.WriteField(key, value); err != nil {
+				h.t.Fatal(err)
+			}
+		}
+	}
+	part, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	if _, err := part.Write(content); err != nil {
+		h.t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		h.t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	return h.serve(req, token)
+}
+
+func (h *harness) serve(req *http.Request, token string) *httptest.ResponseRecorder {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	rec := httptest.NewRecorder()
+	h.handler.ServeHTTP(rec, req)
+
+	return rec
+}
+
+// expect fails the test unless rec has the HTTP status and the pkg/e code
+func (h *harness) expect(rec *httptest.ResponseRecorder, status, code int) *response {
+	h.t.Helper()
+
+	var resp response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		h.t.Fatalf("response %q isn't JSON: %v", rec.Body, err)
+	}
+	if rec.Code != status || resp.Code != code {
+		h.t.Fatalf("got HTTP %d code %d, want HTTP %d code %d: %s", rec.Code, resp.Code, status, code, rec.Body)
+	}
+
+	return &resp
+}
+
+// decode reads data into v
+func (r *response) decode(t *testing.T, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal(r.Data, v); err != nil {
+		t.Fatalf("data %s: %v", r.Data, err)
+	}
+}
+
+// list is the data of GET /api/v1/tags and GET /api/v1/articles, values collects
+// one field of every item
+type list struct {
+	Lists []map[string]interface{} `json:"lists"`
+	Total int                      `json:"total"`
+}
+
+func (l *list) values(key string) []string {
+	var values []string
+	for _, item := range l.Lists {
+		values = append(values, fmt.Sprint(item[key]))
+	}
+
+	return values
+}
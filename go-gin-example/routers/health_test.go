@@ -0,0 +1,31 @@
+package routers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/EGGYC/go-gin-example/pkg/e"
+	"github.com/EGGYC/go-gin-example/pkg/health"
+)
+
+// the harness caches in memory and has no Redis, Redis is optional then
+func TestReadyzWithoutRedis(t *testing.T) {
+	h := newHarness(t)
+
+	rec := h.do(http.MethodGet, "/readyz", "", nil)
+	var results []health.Result
+	h.expect(rec, http.StatusOK, e.SUCCESS).decode(t, &results)
+
+	byName := make(map[string]health.Result)
+	for _, result := range results {
+		byName[result.Name] = result
+	}
+	if redis := byName["redis"]; redis.OK || !redis.Degraded {
+		t.Errorf("redis = %+v, want degraded", redis)
+	}
+	for _, name := range []string{"database", "runtime"} {
+		if !byName[name].OK {
+			t.Errorf("%s = %+v, want ok", name, byName[name])
+		}
+	}
+}
@@ -3,10 +3,15 @@ package routers
 
 import (
 	_ "github.com/EGGYC/go-gin-example/docs"
+	"github.com/EGGYC/go-gin-example/middleware/accesslog"
+	"github.com/EGGYC/go-gin-example/middleware/instrument"
 	"github.com/EGGYC/go-gin-example/middleware/jwt"
+	"github.com/EGGYC/go-gin-example/middleware/permission"
+	"github.com/EGGYC/go-gin-example/middleware/requestid"
+	"github.com/EGGYC/go-gin-example/pkg/container"
 	"github.com/EGGYC/go-gin-example/pkg/export"
+	"github.com/EGGYC/go-gin-example/pkg/metrics"
 	"github.com/EGGYC/go-gin-example/pkg/qrcode"
-	"github.com/EGGYC/go-gin-example/pkg/setting"
 	"github.com/EGGYC/go-gin-example/pkg/upload"
 	"github.com/EGGYC/go-gin-example/routers/api"
 	"github.com/EGGYC/go-gin-example/routers/api/v1"
@@ -17,52 +22,90 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func InitRouter() *gin.Engine {
+// InitRouter registers the routes, the handlers use the services of a
+func InitRouter(a *container.App) *gin.Engine {
 	r := gin.New()
+	// 全局中间件，必须在注册任何路由之前加入，否则之前注册的路由不会经过它们
+	r.Use(requestid.RequestID(a.Logger))
+	// AccessLog 把请求写入 runtime/logs 下的 access 日志
+	r.Use(accesslog.AccessLog())
+	// Metrics 记录每个路由的请求数与耗时，由 /metrics 暴露
+	r.Use(instrument.Metrics())
+	// Recovery 中间件会 recover 任何 panic。如果有 panic 的话，会写入 500。
+	r.Use(gin.Recovery())
+
+	gin.SetMode(a.Config.Server.RunMode)
 
-	r.StaticFS("/export", http.Dir(export.GetExcelFullPath()))
-	r.StaticFS("/upload/images", http.Dir(upload.GetImageFullPath()))
-	r.StaticFS("/qrcode", http.Dir(qrcode.GetQrCodeFullPath()))
+	// api 与 v1 中需要服务的 handler 由 Handler 提供
+	apiH := api.New(a)
+	v1H := v1.New(a)
 
-	r.POST("/auth", api.GetAuth)
+	r.StaticFS("/export", http.Dir(export.GetExcelFullPath(&a.Config.App)))
+	r.StaticFS("/upload/images", http.Dir(upload.GetImageFullPath(&a.Config.App)))
+	r.StaticFS("/qrcode", http.Dir(qrcode.GetQrCodeFullPath(&a.Config.App)))
+
+	r.POST("/auth", apiH.GetAuth)
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-	r.POST("/upload", api.UploadImage)
-	// 全局中间件
-	// Logger 中间件将日志写入 gin.DefaultWriter，即使你将 GIN_MODE 设置为 release。
-	// By default gin.DefaultWriter = os.Stdout
-	r.Use(gin.Logger())
-	// Recovery 中间件会 recover 任何 panic。如果有 panic 的话，会写入 500。
-	r.Use(gin.Recovery())
+	r.POST("/upload", apiH.UploadImage)
+	// Prometheus 指标，[metrics] Token 非空时需要 Bearer token
+	r.GET("/metrics", instrument.RequireToken(a.Config.Metrics.Token), gin.WrapH(metrics.Handler()))
+	// 存活、就绪探针与构建信息
+	r.GET("/healthz", api.Healthz)
+	r.GET("/readyz", apiH.Readyz)
+	r.GET("/version", api.Version)
 
-	gin.SetMode(setting.ServerSetting.RunMode)
+	r.GET("/auth", apiH.GetAuth)
+	r.POST("/auth/register", apiH.Register)
+	r.POST("/auth/refresh", apiH.RefreshToken)
 
-	r.GET("/auth", api.GetAuth)
+	auth := r.Group("/auth")
+	auth.Use(jwt.JWT(a))
+	{
+		//修改当前用户密码
+		auth.PUT("/password", apiH.ChangePassword)
+		//注销当前 token
+		auth.POST("/logout", apiH.Logout)
+	}
 
 	apiv1 := r.Group("/api/v1")
-	apiv1.Use(jwt.JWT()) // 把中间件加入到路由中
+	apiv1.Use(jwt.JWT(a)) // 把中间件加入到路由中
 	{
+		// permission.Require 声明每个路由需要的权限，角色与权限的对应见 middleware/permission
 		//获取标签列表
-		apiv1.GET("/tags", v1.GetTags)
+		apiv1.GET("/tags", permission.Require(permission.TAG_READ), v1H.GetTags)
 		//新建标签
-		apiv1.POST("/tags", v1.AddTag)
+		apiv1.POST("/tags", permission.Require(permission.TAG_WRITE), v1H.AddTag)
 		//更新指定标签
-		apiv1.PUT("/tags/:id", v1.EditTag)
+		apiv1.PUT("/tags/:id", permission.Require(permission.TAG_WRITE), v1H.EditTag)
 		//删除指定标签
-		apiv1.DELETE("/tags/:id", v1.DeleteTag)
+		apiv1.DELETE("/tags/:id", permission.Require(permission.TAG_WRITE), v1H.DeleteTag)
+		//导出标签
+		apiv1.POST("/tags/export", permission.Require(permission.TAG_READ), v1H.ExportTag)
+		//导入标签
+		apiv1.POST("/tags/import", permission.Require(permission.TAG_WRITE), v1H.ImportTag)
 
 		//获取文章列表
-		apiv1.GET("/articles", v1.GetArticles)
+		apiv1.GET("/articles", permission.Require(permission.ARTICLE_READ), v1H.GetArticles)
+		//搜索文章，按相关度排序并高亮匹配的词
+		apiv1.GET("/articles/search", permission.Require(permission.ARTICLE_READ), v1H.SearchArticles)
 		//获取指定文章
-		apiv1.GET("/articles/:id", v1.GetArticle)
+		apiv1.GET("/articles/:id", permission.Require(permission.ARTICLE_READ), v1H.GetArticle)
 		//新建文章
-		apiv1.POST("/articles", v1.AddArticle)
-		//更新指定文章
-		apiv1.PUT("/articles/:id", v1.EditArticle)
-		//删除指定文章
-		apiv1.DELETE("/articles/:id", v1.DeleteArticle)
+		apiv1.POST("/articles", permission.Require(permission.ARTICLE_CREATE), v1H.AddArticle)
+		//更新指定文章，只有 ARTICLE_EDIT_OWN 权限时只能修改自己创建的文章
+		apiv1.PUT("/articles/:id", permission.Require(permission.ARTICLE_EDIT, permission.ARTICLE_EDIT_OWN), v1H.EditArticle)
+		//删除指定文章，只有 ARTICLE_EDIT_OWN 权限时只能删除自己创建的文章
+		apiv1.DELETE("/articles/:id", permission.Require(permission.ARTICLE_EDIT, permission.ARTICLE_EDIT_OWN), v1H.DeleteArticle)
+		//导出文章
+		apiv1.POST("/articles/export", permission.Require(permission.ARTICLE_READ), v1H.ExportArticle)
+		//导入文章，导入会覆盖已有的文章
+		apiv1.POST("/articles/import", permission.Require(permission.ARTICLE_EDIT), v1H.ImportArticle)
 
 		//生成文章海报
-		apiv1.POST("/articles/poster/generate", v1.GenerateArticlePoster)
+		apiv1.POST("/articles/poster/generate", permission.Require(permission.ARTICLE_READ), v1H.GenerateArticlePoster)
+
+		//修改用户角色
+		apiv1.PUT("/users/:id/role", permission.Require(permission.USER_MANAGE), v1H.EditUserRole)
 	}
 
 	return r
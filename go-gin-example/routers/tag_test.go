@@ -0,0 +1,283 @@
+package routers_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tealeg/xlsx"
+
+	"github.com/EGGYC/go-gin-example/pkg/e"
+	"github.com/EGGYC/go-gin-example/pkg/importer"
+	"github.com/EGGYC/go-gin-example/pkg/setting"
+	"github.com/EGGYC/go-gin-example/service/tag_service"
+)
+
+func TestGetTags(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(readerUser)
+
+	var tags list
+	h.expect(h.do(http.MethodGet, "/api/v1/tags", token, nil), http.StatusOK, e.SUCCESS).decode(t, &tags)
+	if tags.Total != 2 || len(tags.Lists) != 2 {
+		t.Fatalf("got %d tags of %d, want 2 of 2", len(tags.Lists), tags.Total)
+	}
+
+	h.expect(h.do(http.MethodGet, "/api/v1/tags?state=1", token, nil), http.StatusOK, e.SUCCESS).decode(t, &tags)
+	if names := tags.values("name"); tags.Total != 1 || len(names) != 1 || names[0] != "go" {
+		t.Fatalf("state=1: got %v of %d, want [go] of 1", names, tags.Total)
+	}
+
+	h.expect(h.do(http.MethodGet, "/api/v1/tags?name=empty", token, nil), http.StatusOK, e.SUCCESS).decode(t, &tags)
+	if names := tags.values("name"); tags.Total != 1 || len(names) != 1 || names[0] != "empty" {
+		t.Fatalf("name=empty: got %v of %d, want [empty] of 1", names, tags.Total)
+	}
+}
+
+func TestGetTagsPage(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(readerUser)
+
+	// the offset and the limit come from the same snapshot, as after a reload of PageSize
+	cfg := *h.app.Config
+	cfg.App.PageSize = 1
+	h.app.Settings = func() *setting.Snapshot { return &cfg }
+
+	var names []string
+	for _, page := range []string{"1", "2"} {
+		var tags list
+		h.expect(h.do(http.MethodGet, "/api/v1/tags?page="+page, token, nil), http.StatusOK, e.SUCCESS).decode(t, &tags)
+		if tags.Total != 2 || len(tags.Lists) != 1 {
+			t.Fatalf("page %s: got %d tags of %d, want 1 of 2", page, len(tags.Lists), tags.Total)
+		}
+		names = append(names, tags.values("name")...)
+	}
+	if names[0] == names[1] {
+		t.Fatalf("got %v, want a different tag on each page", names)
+	}
+}
+
+func TestGetTagsFail(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(readerUser)
+
+	// the list is cached, Count still reads the table
+	h.expect(h.do(http.MethodGet, "/api/v1/tags", token, nil), http.StatusOK, e.SUCCESS)
+	h.dropTable("tag")
+	h.expect(h.do(http.MethodGet, "/api/v1/tags", token, nil), http.StatusInternalServerError, e.ERROR_COUNT_TAG_FAIL)
+
+	h.expect(h.do(http.MethodGet, "/api/v1/tags?state=0", token, nil), http.StatusInternalServerError, e.ERROR_GET_TAGS_FAIL)
+}
+
+func TestAddTag(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(adminUser)
+
+	// the new tag is listed right away, adding it clears the cached lists
+	h.expect(h.do(http.MethodGet, "/api/v1/tags?name=gin", token, nil), http.StatusOK, e.SUCCESS)
+	h.expect(h.do(http.MethodPost, "/api/v1/tags", token, url.Values{"name": {"gin"}, "state": {"1"}}), http.StatusOK, e.SUCCESS)
+
+	var tags list
+	h.expect(h.do(http.MethodGet, "/api/v1/tags?name=gin", token, nil), http.StatusOK, e.SUCCESS).decode(t, &tags)
+	if tags.Total != 1 || len(tags.Lists) != 1 {
+		t.Fatalf("got %d tags of %d named gin, want 1 of 1", len(tags.Lists), tags.Total)
+	}
+	if createdBy := tags.Lists[0]["created_by"]; createdBy != adminUser.Username {
+		t.Errorf("created_by = %v, want %s", createdBy, adminUser.Username)
+	}
+
+	h.expect(h.do(http.MethodPost, "/api/v1/tags", token, url.Values{"name": {"go"}}), http.StatusOK, e.ERROR_EXIST_TAG)
+	h.expect(h.do(http.MethodPost, "/api/v1/tags", token, url.Values{"name": {""}}), http.StatusBadRequest, e.INVALID_PARAMS)
+	h.expect(h.do(http.MethodPost, "/api/v1/tags", token, url.Values{"name": {"rust"}, "state": {"2"}}), http.StatusBadRequest, e.INVALID_PARAMS)
+}
+
+func TestAddTagFail(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(adminUser)
+
+	h.failOn("tag", "INSERT")
+	h.expect(h.do(http.MethodPost, "/api/v1/tags", token, url.Values{"name": {"gin"}}), http.StatusInternalServerError, e.ERROR_ADD_TAG_FAIL)
+
+	h.dropTable("tag")
+	h.expect(h.do(http.MethodPost, "/api/v1/tags", token, url.Values{"name": {"gin"}}), http.StatusInternalServerError, e.ERROR_EXIST_TAG_FAIL)
+}
+
+func TestEditTag(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(adminUser)
+	path := "/api/v1/tags/" + strconv.Itoa(h.fixtures.EmptyTag)
+
+	h.expect(h.do(http.MethodPut, path, token, url.Values{"name": {"renamed"}, "state": {"1"}}), http.StatusOK, e.SUCCESS)
+
+	var tags list
+	h.expect(h.do(http.MethodGet, "/api/v1/tags?name=renamed", token, nil), http.StatusOK, e.SUCCESS).decode(t, &tags)
+	if tags.Total != 1 || len(tags.Lists) != 1 {
+		t.Fatalf("got %d tags of %d named renamed, want 1 of 1", len(tags.Lists), tags.Total)
+	}
+	if tag := tags.Lists[0]; tag["state"] != float64(1) || tag["modified_by"] != adminUser.Username {
+		t.Errorf("got state %v modified_by %v, want 1 %s", tag["state"], tag["modified_by"], adminUser.Username)
+	}
+
+	h.expect(h.do(http.MethodPut, "/api/v1/tags/9999", token, url.Values{"name": {"missing"}}), http.StatusOK, e.ERROR_NOT_EXIST_TAG)
+	h.expect(h.do(http.MethodPut, "/api/v1/tags/0", token, url.Values{"name": {"zero"}}), http.StatusBadRequest, e.INVALID_PARAMS)
+	h.expect(h.do(http.MethodPut, path, token, url.Values{"name": {""}}), http.StatusBadRequest, e.INVALID_PARAMS)
+}
+
+func TestEditTagFail(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(adminUser)
+	path := "/api/v1/tags/" + strconv.Itoa(h.fixtures.GoTag)
+
+	h.failOn("tag", "UPDATE")
+	h.expect(h.do(http.MethodPut, path, token, url.Values{"name": {"golang"}}), http.StatusInternalServerError, e.ERROR_EDIT_TAG_FAIL)
+
+	h.dropTable("tag")
+	h.expect(h.do(http.MethodPut, path, token, url.Values{"name": {"golang"}}), http.StatusInternalServerError, e.ERROR_EXIST_TAG_FAIL)
+}
+
+func TestDeleteTag(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(adminUser)
+	path := "/api/v1/tags/" + strconv.Itoa(h.fixtures.EmptyTag)
+
+	h.expect(h.do(http.MethodDelete, path, token, nil), http.StatusOK, e.SUCCESS)
+	h.expect(h.do(http.MethodDelete, path, token, nil), http.StatusOK, e.ERROR_NOT_EXIST_TAG)
+
+	var tags list
+	h.expect(h.do(http.MethodGet, "/api/v1/tags", token, nil), http.StatusOK, e.SUCCESS).decode(t, &tags)
+	if names := tags.values("name"); tags.Total != 1 || len(names) != 1 || names[0] != "go" {
+		t.Fatalf("got %v of %d, want [go] of 1", names, tags.Total)
+	}
+
+	h.expect(h.do(http.MethodDelete, "/api/v1/tags/0", token, nil), http.StatusBadRequest, e.INVALID_PARAMS)
+}
+
+func TestDeleteTagFail(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(adminUser)
+	path := "/api/v1/tags/" + strconv.Itoa(h.fixtures.EmptyTag)
+
+	h.failOn("tag", "UPDATE")
+	h.expect(h.do(http.MethodDelete, path, token, nil), http.StatusInternalServerError, e.ERROR_DELETE_TAG_FAIL)
+
+	h.dropTable("tag")
+	h.expect(h.do(http.MethodDelete, path, token, nil), http.StatusInternalServerError, e.ERROR_EXIST_TAG_FAIL)
+}
+
+func TestExportTag(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(readerUser)
+
+	var data map[string]string
+	h.expect(h.do(http.MethodPost, "/api/v1/tags/export", token, url.Values{"state": {"1"}}), http.StatusOK, e.SUCCESS).decode(t, &data)
+
+	name := filepath.Join(h.app.Config.App.RuntimeRootPath, data["export_save_url"])
+	f, err := xlsx.OpenFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := f.Sheet[tag_service.SHEET_NAME].Rows
+	if len(rows) != 2 || rows[1].Cells[1].Value != "go" {
+		t.Fatalf("got %d rows, want the header and go", len(rows))
+	}
+}
+
+func TestExportTagFail(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(readerUser)
+
+	h.dropTable("tag")
+	h.expect(h.do(http.MethodPost, "/api/v1/tags/export", token, nil), http.StatusInternalServerError, e.ERROR_EXPORT_TAG_FAIL)
+}
+
+func TestImportTag(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(adminUser)
+	content := tagSheet(t, []string{"1", "gin"}, []string{"2", "go"}, []string{"3", strings.Repeat("x", 101), "someone"})
+
+	var report importer.Report
+	h.expect(h.upload("/api/v1/tags/import", token, "tags.xlsx", content, url.Values{"dry_run": {"true"}}),
+		http.StatusOK, e.SUCCESS).decode(t, &report)
+	if !report.DryRun || report.Created != 1 || report.Skipped != 1 || report.Invalid != 1 {
+		t.Fatalf("dry run: got %+v, want 1 created, 1 skipped and 1 invalid", report)
+	}
+
+	h.expect(h.upload("/api/v1/tags/import", token, "tags.xlsx", content, nil), http.StatusOK, e.SUCCESS).decode(t, &report)
+	if report.DryRun || report.Created != 1 {
+		t.Fatalf("got %+v, want 1 created", report)
+	}
+
+	var tags list
+	h.expect(h.do(http.MethodGet, "/api/v1/tags?name=gin", token, nil), http.StatusOK, e.SUCCESS).decode(t, &tags)
+	if tags.Total != 1 || tags.Lists[0]["created_by"] != adminUser.Username {
+		t.Fatalf("got %v, want gin created by %s", tags.Lists, adminUser.Username)
+	}
+
+	h.expect(h.upload("/api/v1/tags/import", token, "tags.xlsx", content, url.Values{"dry_run": {"maybe"}}),
+		http.StatusBadRequest, e.INVALID_PARAMS)
+	h.expect(h.do(http.MethodPost, "/api/v1/tags/import", token, url.Values{}), http.StatusBadRequest, e.INVALID_PARAMS)
+}
+
+func TestImportTagAuthors(t *testing.T) {
+	h := newHarness(t)
+
+	// only a user manager keeps the created_by of the file
+	for _, tt := range []struct {
+		user      user
+		name      string
+		createdBy string
+	}{
+		{editorUser, "gin", editorUser.Username},
+		{adminUser, "gorm", "someone"},
+	} {
+		token := h.login(tt.user)
+		h.expect(h.upload("/api/v1/tags/import", token, "tags.xlsx", tagSheet(t, []string{"1", tt.name, "someone"}), nil),
+			http.StatusOK, e.SUCCESS)
+
+		var tags list
+		h.expect(h.do(http.MethodGet, "/api/v1/tags?name="+tt.name, token, nil), http.StatusOK, e.SUCCESS).decode(t, &tags)
+		if got := tags.values("created_by"); len(got) != 1 || got[0] != tt.createdBy {
+			t.Errorf("%s imported by %s: got created_by %v, want %s", tt.name, tt.user.Username, got, tt.createdBy)
+		}
+	}
+}
+
+func TestImportTagFail(t *testing.T) {
+	h := newHarness(t)
+	token := h.login(adminUser)
+
+	h.expect(h.upload("/api/v1/tags/import", token, "tags.xlsx", []byte("not a spreadsheet"), nil),
+		http.StatusInternalServerError, e.ERROR_IMPORT_TAG_FAIL)
+
+	h.failOn("tag", "INSERT")
+	h.expect(h.upload("/api/v1/tags/import", token, "tags.xlsx", tagSheet(t, []string{"1", "gin"}), nil),
+		http.StatusInternalServerError, e.ERROR_IMPORT_TAG_FAIL)
+}
+
+// tagSheet writes rows after the header of the sheet read by POST /api/v1/tags/import.
+// Leave out trailing columns rather than writing empty cells, excelize reads an empty
+// cell written by tealeg/xlsx as the first shared string
+func tagSheet(t *testing.T, rows ...[]string) []byte {
+	t.Helper()
+
+	f := xlsx.NewFile()
+	sheet, err := f.AddSheet(tag_service.SHEET_NAME)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheet.AddRow().WriteSlice(&[]string{"ID", "名称", "创建人"}, -1)
+	for i := range rows {
+		sheet.AddRow().WriteSlice(&rows[i], -1)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,46 @@
+package routers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/EGGYC/go-gin-example/models"
+	"github.com/EGGYC/go-gin-example/pkg/e"
+)
+
+func TestSeededAuthIsReader(t *testing.T) {
+	h := newHarness(t)
+
+	// the test account of the migrations has a public password, it must not manage anything
+	token := h.login(user{Username: "test", Password: "test123"})
+	path := fmt.Sprintf("/api/v1/users/%d/role", h.lookupID("auth", "username", "test"))
+	h.expect(h.do(http.MethodPut, path, token, url.Values{"role": {models.ROLE_ADMIN}}),
+		http.StatusForbidden, e.ERROR_AUTH_PERMISSION)
+}
+
+func TestEditUserRole(t *testing.T) {
+	h := newHarness(t)
+	admin := h.login(adminUser)
+	rolePath := func(u user) string {
+		return fmt.Sprintf("/api/v1/users/%d/role", h.lookupID("auth", "username", u.Username))
+	}
+
+	// the only admin can't demote themselves
+	h.expect(h.do(http.MethodPut, rolePath(adminUser), admin, url.Values{"role": {models.ROLE_READER}}),
+		http.StatusOK, e.ERROR_LAST_ADMIN)
+
+	// with a second admin they can
+	h.expect(h.do(http.MethodPut, rolePath(editorUser), admin, url.Values{"role": {models.ROLE_ADMIN}}), http.StatusOK, e.SUCCESS)
+	h.expect(h.do(http.MethodPut, rolePath(adminUser), admin, url.Values{"role": {models.ROLE_EDITOR}}), http.StatusOK, e.SUCCESS)
+	if auth, err := h.app.DB.GetAuthByUsername(adminUser.Username); err != nil || auth.Role != models.ROLE_EDITOR {
+		t.Fatalf("got %+v, %v, want the role %s", auth, err, models.ROLE_EDITOR)
+	}
+
+	// the new admin is the last one now, its role is reloaded by logging in again
+	editor := h.login(editorUser)
+	h.expect(h.do(http.MethodPut, rolePath(editorUser), editor, url.Values{"role": {models.ROLE_READER}}),
+		http.StatusOK, e.ERROR_LAST_ADMIN)
+	h.expect(h.do(http.MethodPut, rolePath(readerUser), editor, url.Values{"role": {"owner"}}), http.StatusBadRequest, e.INVALID_PARAMS)
+}
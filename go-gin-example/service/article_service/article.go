@@ -1,15 +1,36 @@
 package article_service
 
 import (
-	"encoding/json"
-
 	"github.com/EGGYC/go-gin-example/models"
-	"github.com/EGGYC/go-gin-example/pkg/gredis"
-	"github.com/EGGYC/go-gin-example/pkg/logging"
+	"github.com/EGGYC/go-gin-example/pkg/search"
+	"github.com/EGGYC/go-gin-example/pkg/setting"
 	"github.com/EGGYC/go-gin-example/service/cache_service"
 )
 
+// Service reads and writes the articles in db and keeps cache and index consistent with them
+type Service struct {
+	settings *setting.App
+	db       *models.DB
+	cache    *cache_service.Cache
+	index    search.Index
+}
+
+// New returns the article service, a nil cache caches nothing and a nil index finds nothing
+func New(s *setting.App, db *models.DB, c *cache_service.Cache, idx search.Index) *Service {
+	if c == nil {
+		c = cache_service.New(nil, &setting.Cache{})
+	}
+	if idx == nil {
+		idx = search.Nop{}
+	}
+
+	return &Service{settings: s, db: db, cache: c, index: idx}
+}
+
+// Article holds the parameters of an operation, Service must be set
 type Article struct {
+	*Service
+
 	ID            int
 	TagID         int
 	Title         string
@@ -19,6 +40,10 @@ type Article struct {
 	State         int
 	CreatedBy     string
 	ModifiedBy    string
+	// KeepAuthors makes Import keep the created_by and modified_by of the file instead
+	// of recording CreatedBy and ModifiedBy, only a user manager may claim a change was
+	// made by someone else
+	KeepAuthors bool
 
 	PageNum  int
 	PageSize int
@@ -35,15 +60,20 @@ func (a *Article) Add() error {
 		"state":           a.State,
 	}
 
-	if err := models.AddArticle(article); err != nil {
+	id, err := a.db.AddArticle(article)
+	if err != nil {
 		return err
 	}
 
+	// the new ID may have been cached as missing
+	a.ID = id
+	a.invalidate(a.ID)
+	a.putIndex(a.document())
 	return nil
 }
 
 func (a *Article) Edit() error {
-	return models.EditArticle(a.ID, map[string]interface{}{
+	err := a.db.EditArticle(a.ID, map[string]interface{}{
 		"tag_id":          a.TagID,
 		"title":           a.Title,
 		"desc":            a.Desc,
@@ -52,36 +82,38 @@ func (a *Article) Edit() error {
 		"state":           a.State,
 		"modified_by":     a.ModifiedBy,
 	})
+	if err != nil {
+		return err
+	}
+
+	a.invalidate(a.ID)
+	a.putIndex(a.document())
+	return nil
 }
 
+// Get returns the article with ID, it is nil when the article doesn't exist
 func (a *Article) Get() (*models.Article, error) {
-	var cacheArticle *models.Article
+	var article *models.Article
 
 	cache := cache_service.Article{ID: a.ID}
 	key := cache.GetArticleKey()
-	if gredis.Exists(key) {
-		data, err := gredis.Get(key)
-		if err != nil {
-			logging.Info(err)
-		} else {
-			json.Unmarshal(data, &cacheArticle)
-			return cacheArticle, nil
+	found, err := a.cache.Fetch(key, &article, func() (interface{}, error) {
+		article, err := a.db.GetArticle(a.ID)
+		if err != nil || article.ID == 0 {
+			return nil, err
 		}
-	}
 
-	article, err := models.GetArticle(a.ID)
-	if err != nil {
+		return article, nil
+	})
+	if err != nil || !found {
 		return nil, err
 	}
 
-	gredis.Set(key, article, 3600)
 	return article, nil
 }
 
 func (a *Article) GetAll() ([]*models.Article, error) {
-	var (
-		articles, cacheArticles []*models.Article
-	)
+	var articles []*models.Article
 
 	cache := cache_service.Article{
 		TagID: a.TagID,
@@ -91,35 +123,42 @@ func (a *Article) GetAll() ([]*models.Article, error) {
 		PageSize: a.PageSize,
 	}
 	key := cache.GetArticlesKey()
-	if gredis.Exists(key) {
-		data, err := gredis.Get(key)
-		if err != nil {
-			logging.Info(err)
-		} else {
-			json.Unmarshal(data, &cacheArticles)
-			return cacheArticles, nil
-		}
-	}
-
-	articles, err := models.GetArticles(a.PageNum, a.PageSize, a.getMaps())
+	_, err := a.cache.Fetch(key, &articles, func() (interface{}, error) {
+		return a.db.GetArticles(a.PageNum, a.PageSize, a.getMaps())
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	gredis.Set(key, articles, 3600)
 	return articles, nil
 }
 
 func (a *Article) Delete() error {
-	return models.DeleteArticle(a.ID)
+	if err := a.db.DeleteArticle(a.ID); err != nil {
+		return err
+	}
+
+	a.invalidate(a.ID)
+	a.deleteIndex(a.ID)
+	return nil
 }
 
 func (a *Article) ExistByID() (bool, error) {
-	return models.ExistArticleByID(a.ID)
+	return a.db.ExistArticleByID(a.ID)
+}
+
+// IsCreatedBy checks the article with ID was created by username
+func (a *Article) IsCreatedBy(username string) (bool, error) {
+	article, err := a.db.GetArticle(a.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return article.ID > 0 && article.CreatedBy == username, nil
 }
 
 func (a *Article) Count() (int, error) {
-	return models.GetArticleTotal(a.getMaps())
+	return a.db.GetArticleTotal(a.getMaps())
 }
 
 func (a *Article) getMaps() map[string]interface{} {
@@ -134,3 +173,28 @@ func (a *Article) getMaps() map[string]interface{} {
 
 	return maps
 }
+
+// invalidate drops the cached articles with ids and every cached list, a write can
+// move an article in or out of any page
+func (s *Service) invalidate(ids ...int) {
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		cache := cache_service.Article{ID: id}
+		keys = append(keys, cache.GetArticleKey())
+	}
+	s.cache.Delete(keys...)
+
+	cache := cache_service.Article{}
+	s.cache.DeletePrefix(cache.GetArticlesKeyPrefix())
+}
+
+// InvalidateByTag drops the cached articles that embed the tag, after the tag changed
+func (s *Service) InvalidateByTag(tagID int) error {
+	ids, err := s.db.GetArticleIDsByTagID(tagID)
+	if err != nil {
+		return err
+	}
+
+	s.invalidate(ids...)
+	return nil
+}
@@ -0,0 +1,177 @@
+package article_service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/tealeg/xlsx"
+
+	"github.com/EGGYC/go-gin-example/models"
+	"github.com/EGGYC/go-gin-example/pkg/export"
+	"github.com/EGGYC/go-gin-example/pkg/file"
+)
+
+// SHEET_NAME is the sheet written by Export and read by Import
+const SHEET_NAME = "文章信息"
+
+// columns is the header of the xlsx and CSV files, JSON Lines use the same keys
+var columns = []string{
+	"id", "tag_id", "title", "desc", "content", "cover_image_url", "state",
+	"created_by", "created_on", "modified_by", "modified_on",
+}
+
+// record is an article as it is exported and imported
+type record struct {
+	ID            int    `json:"id"`
+	TagID         int    `json:"tag_id" valid:"Required;Min(1)"`
+	Title         string `json:"title" valid:"Required;MaxSize(100)"`
+	Desc          string `json:"desc" valid:"Required;MaxSize(255)"`
+	Content       string `json:"content" valid:"Required;MaxSize(65535)"`
+	CoverImageUrl string `json:"cover_image_url" valid:"Required;MaxSize(255)"`
+	State         int    `json:"state" valid:"Range(0,1)"`
+	CreatedBy     string `json:"created_by" valid:"Required;MaxSize(100)"`
+	CreatedOn     int    `json:"created_on"`
+	ModifiedBy    string `json:"modified_by" valid:"MaxSize(100)"`
+	ModifiedOn    int    `json:"modified_on"`
+}
+
+func newRecord(a *models.Article) *record {
+	return &record{
+		ID:            a.ID,
+		TagID:         a.TagID,
+		Title:         a.Title,
+		Desc:          a.Desc,
+		Content:       a.Content,
+		CoverImageUrl: a.CoverImageUrl,
+		State:         a.State,
+		CreatedBy:     a.CreatedBy,
+		CreatedOn:     a.CreatedOn,
+		ModifiedBy:    a.ModifiedBy,
+		ModifiedOn:    a.ModifiedOn,
+	}
+}
+
+// values the record in the order of columns
+func (r *record) values() []string {
+	return []string{
+		strconv.Itoa(r.ID),
+		strconv.Itoa(r.TagID),
+		r.Title,
+		r.Desc,
+		r.Content,
+		r.CoverImageUrl,
+		strconv.Itoa(r.State),
+		r.CreatedBy,
+		strconv.Itoa(r.CreatedOn),
+		r.ModifiedBy,
+		strconv.Itoa(r.ModifiedOn),
+	}
+}
+
+// Export writes the articles matching TagID and State to the export directory
+// in the given format, it returns the file name
+func (a *Article) Export(format string) (string, error) {
+	ext, ok := export.GetFormatExt(format)
+	if !ok {
+		return "", fmt.Errorf("unsupported export format: %q", format)
+	}
+
+	articles, err := a.db.GetArticles(0, 0, a.getMaps())
+	if err != nil {
+		return "", err
+	}
+
+	records := make([]*record, 0, len(articles))
+	for _, article := range articles {
+		records = append(records, newRecord(article))
+	}
+
+	time := strconv.Itoa(int(time.Now().Unix()))
+	filename := "articles-" + time + ext
+
+	dirFullPath := export.GetExcelFullPath(a.settings)
+	err = file.IsNotExistMkDir(dirFullPath)
+	if err != nil {
+		return "", err
+	}
+
+	switch format {
+	case export.FORMAT_XLSX:
+		err = writeXlsx(dirFullPath+filename, records)
+	case export.FORMAT_CSV:
+		err = writeCsv(dirFullPath+filename, records)
+	case export.FORMAT_JSONL:
+		err = writeJsonl(dirFullPath+filename, records)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return filename, nil
+}
+
+func writeXlsx(path string, records []*record) error {
+	xlsFile := xlsx.NewFile()
+	sheet, err := xlsFile.AddSheet(SHEET_NAME)
+	if err != nil {
+		return err
+	}
+
+	rows := [][]string{columns}
+	for _, r := range records {
+		rows = append(rows, r.values())
+	}
+
+	for _, values := range rows {
+		row := sheet.AddRow()
+		for _, value := range values {
+			row.AddCell().Value = value
+		}
+	}
+
+	return xlsFile.Save(path)
+}
+
+func writeCsv(path string, records []*record) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.Write(columns); err != nil {
+		return err
+	}
+	for _, r := range records {
+		if err := w.Write(r.values()); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+
+	return w.Error()
+}
+
+func writeJsonl(path string, records []*record) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,343 @@
+package article_service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/360EntSecGroup-Skylar/excelize"
+	"github.com/astaxie/beego/validation"
+	"github.com/jinzhu/gorm"
+
+	"github.com/EGGYC/go-gin-example/models"
+	"github.com/EGGYC/go-gin-example/pkg/export"
+	"github.com/EGGYC/go-gin-example/pkg/importer"
+)
+
+// requiredColumns must be in the header of xlsx and CSV files, the others are optional
+var requiredColumns = []string{"tag_id", "title", "desc", "content", "cover_image_url"}
+
+// parsedRow is a record read from the file, or the reason it could not be read
+type parsedRow struct {
+	number int
+	record *record
+	err    error
+}
+
+// Import reads articles written by Export and upserts them by ID in one transaction.
+// Rows with an ID of an existing article update it, the others are created, keeping
+// their ID when one is given. Articles are created by a.CreatedBy and updated by
+// a.ModifiedBy, with a.KeepAuthors the created_by and modified_by of a row win when
+// set. Rows whose tag doesn't exist, or whose ID belongs to a deleted article, are
+// reported as invalid.
+// With dryRun every row is checked the same way but the transaction is rolled back
+func (a *Article) Import(r io.Reader, format string, dryRun bool) (*importer.Report, error) {
+	var (
+		rows []*parsedRow
+		err  error
+	)
+	switch format {
+	case export.FORMAT_XLSX:
+		rows, err = readXlsx(r)
+	case export.FORMAT_CSV:
+		rows, err = readCsv(r)
+	case export.FORMAT_JSONL:
+		rows, err = readJsonl(r)
+	default:
+		err = fmt.Errorf("unsupported import format: %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var report *importer.Report
+	err = a.db.Transaction(func(tx *gorm.DB) error {
+		report = &importer.Report{DryRun: dryRun}
+		tags := make(map[int]bool)
+
+		for _, row := range rows {
+			result := &importer.Row{Row: row.number, ID: row.record.ID, Title: row.record.Title}
+			report.Rows = append(report.Rows, result)
+			a.setAuthors(row.record)
+			if row.err == nil {
+				row.err = validate(row.record)
+			}
+			if row.err == nil {
+				row.err = checkTag(tx, tags, row.record.TagID)
+			}
+			if row.err != nil {
+				if _, invalid := row.err.(invalidError); !invalid {
+					return row.err
+				}
+				result.Result = importer.RESULT_INVALID
+				result.Reason = row.err.Error()
+				report.Invalid++
+				continue
+			}
+
+			updated, err := a.upsert(tx, row.record)
+			if _, invalid := err.(invalidError); invalid {
+				row.err = err
+				result.Result = importer.RESULT_INVALID
+				result.Reason = err.Error()
+				report.Invalid++
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("row %d: %v", row.number, err)
+			}
+			if updated {
+				result.Result = importer.RESULT_UPDATED
+				report.Updated++
+			} else {
+				result.Result = importer.RESULT_CREATED
+				report.Created++
+				if !dryRun {
+					result.ID = row.record.ID
+				}
+			}
+		}
+
+		if dryRun {
+			return importer.ErrDryRun
+		}
+
+		return nil
+	})
+	if err != nil && err != importer.ErrDryRun {
+		return nil, err
+	}
+
+	if !dryRun {
+		var ids []int
+		for _, row := range report.Rows {
+			if row.Result != importer.RESULT_INVALID {
+				ids = append(ids, row.ID)
+			}
+		}
+		a.invalidate(ids...)
+
+		// every valid row was written once the transaction committed
+		for _, row := range rows {
+			if row.err == nil {
+				a.putIndex(row.record.document())
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// upsert updates the article with the record's ID when it exists, otherwise it creates one
+// and sets the record's ID to the new article. The ID of a deleted article is an
+// invalidError: an import doesn't restore deleted articles, and creating one with the
+// same ID would conflict with the row until it is purged
+func (a *Article) upsert(tx *gorm.DB, r *record) (bool, error) {
+	if r.ID > 0 {
+		exists, err := models.ExistArticleByIDTx(tx, r.ID)
+		if err != nil {
+			return false, err
+		}
+		if !exists {
+			deleted, err := models.IsArticleDeletedTx(tx, r.ID)
+			if err != nil {
+				return false, err
+			}
+			if deleted {
+				return false, invalidError(fmt.Sprintf("article %d was deleted", r.ID))
+			}
+		}
+
+		if exists {
+			return true, models.EditArticleTx(tx, r.ID, map[string]interface{}{
+				"tag_id":          r.TagID,
+				"title":           r.Title,
+				"desc":            r.Desc,
+				"content":         r.Content,
+				"cover_image_url": r.CoverImageUrl,
+				"state":           r.State,
+				"modified_by":     r.ModifiedBy,
+			})
+		}
+	}
+
+	article := map[string]interface{}{
+		"tag_id":          r.TagID,
+		"title":           r.Title,
+		"desc":            r.Desc,
+		"content":         r.Content,
+		"created_by":      r.CreatedBy,
+		"cover_image_url": r.CoverImageUrl,
+		"state":           r.State,
+	}
+	if r.ID > 0 {
+		article["id"] = r.ID
+	}
+
+	id, err := models.AddArticleTx(tx, article)
+	r.ID = id
+	return false, err
+}
+
+// setAuthors replaces the audit fields read from the file by the importing user, unless
+// a.KeepAuthors keeps the ones that are set
+func (a *Article) setAuthors(r *record) {
+	if !a.KeepAuthors || r.CreatedBy == "" {
+		r.CreatedBy = a.CreatedBy
+	}
+	if !a.KeepAuthors || r.ModifiedBy == "" {
+		r.ModifiedBy = a.ModifiedBy
+	}
+}
+
+// invalidError is a problem with a row, it is reported instead of failing the import
+type invalidError string
+
+func (e invalidError) Error() string {
+	return string(e)
+}
+
+// validate checks a record like AddArticleForm
+func validate(r *record) error {
+	valid := validation.Validation{}
+	ok, err := valid.Valid(r)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return invalidError(valid.Errors[0].Message)
+	}
+
+	return nil
+}
+
+// checkTag checks that the tag exists, the answers are remembered in tags
+func checkTag(tx *gorm.DB, tags map[int]bool, id int) error {
+	exists, ok := tags[id]
+	if !ok {
+		var err error
+		exists, err = models.ExistTagByIDTx(tx, id)
+		if err != nil {
+			return err
+		}
+		tags[id] = exists
+	}
+
+	if !exists {
+		return invalidError(fmt.Sprintf("tag %d does not exist", id))
+	}
+
+	return nil
+}
+
+func readXlsx(r io.Reader) ([]*parsedRow, error) {
+	xlsx, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if xlsx.GetSheetIndex(SHEET_NAME) == 0 {
+		return nil, fmt.Errorf("sheet %s not found", SHEET_NAME)
+	}
+
+	return readTable(xlsx.GetRows(SHEET_NAME))
+}
+
+func readCsv(r io.Reader) ([]*parsedRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	table, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	return readTable(table)
+}
+
+// readTable maps the rows of a table to records by the names in its header row
+func readTable(table [][]string) ([]*parsedRow, error) {
+	if len(table) == 0 {
+		return nil, nil
+	}
+
+	index := make(map[string]int)
+	for i, name := range table[0] {
+		index[strings.TrimSpace(name)] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("missing column %s", name)
+		}
+	}
+
+	var rows []*parsedRow
+	for i, values := range table[1:] {
+		if importer.IsEmptyRow(values) {
+			continue
+		}
+
+		get := func(name string) string {
+			if j, ok := index[name]; ok && j < len(values) {
+				return strings.TrimSpace(values[j])
+			}
+			return ""
+		}
+		atoi := func(name string) (int, error) {
+			value := get(name)
+			if value == "" {
+				return 0, nil
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return 0, invalidError(fmt.Sprintf("%s is not a number: %q", name, value))
+			}
+			return n, nil
+		}
+
+		r := &record{
+			Title:         get("title"),
+			Desc:          get("desc"),
+			Content:       get("content"),
+			CoverImageUrl: get("cover_image_url"),
+			CreatedBy:     get("created_by"),
+			ModifiedBy:    get("modified_by"),
+		}
+		row := &parsedRow{number: i + 2, record: r}
+		if r.ID, row.err = atoi("id"); row.err == nil {
+			if r.TagID, row.err = atoi("tag_id"); row.err == nil {
+				r.State, row.err = atoi("state")
+			}
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func readJsonl(r io.Reader) ([]*parsedRow, error) {
+	var rows []*parsedRow
+	reader := bufio.NewReader(r)
+	for number := 1; ; number++ {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		if strings.TrimSpace(line) != "" {
+			row := &parsedRow{number: number, record: &record{}}
+			if jsonErr := json.Unmarshal([]byte(line), row.record); jsonErr != nil {
+				row.err = invalidError(jsonErr.Error())
+			}
+			rows = append(rows, row)
+		}
+
+		if err == io.EOF {
+			return rows, nil
+		}
+	}
+}
@@ -11,7 +11,6 @@ import (
 
 	"github.com/EGGYC/go-gin-example/pkg/file"
 	"github.com/EGGYC/go-gin-example/pkg/qrcode"
-	"github.com/EGGYC/go-gin-example/pkg/setting"
 )
 
 type ArticlePoster struct {
@@ -94,7 +93,7 @@ type DrawText struct {
 }
 
 func (a *ArticlePosterBg) DrawPoster(d *DrawText, fontName string) error {
-	fontSource := setting.AppSetting.RuntimeRootPath + setting.AppSetting.FontSavePath + fontName
+	fontSource := a.settings.RuntimeRootPath + a.settings.FontSavePath + fontName
 	fontSourceBytes, err := ioutil.ReadFile(fontSource)
 	if err != nil {
 		return err
@@ -134,7 +133,7 @@ func (a *ArticlePosterBg) DrawPoster(d *DrawText, fontName string) error {
 }
 
 func (a *ArticlePosterBg) Generate() (string, string, error) {
-	fullPath := qrcode.GetQrCodeFullPath()
+	fullPath := qrcode.GetQrCodeFullPath(a.settings)
 	fileName, path, err := a.Qr.Encode(fullPath)
 	if err != nil {
 		return "", "", err
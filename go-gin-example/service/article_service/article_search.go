@@ -0,0 +1,84 @@
+package article_service
+
+import (
+	"github.com/EGGYC/go-gin-example/models"
+	"github.com/EGGYC/go-gin-example/pkg/logging"
+	"github.com/EGGYC/go-gin-example/pkg/search"
+)
+
+// SearchResult is an article matching a search, Highlights holds a snippet of every
+// field that matched: title, desc and content
+type SearchResult struct {
+	*models.Article
+
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// Search ranks the live articles matching query, PageNum is the offset of the page and
+// PageSize its length. total counts the matches of every page
+func (a *Article) Search(query string) (results []*SearchResult, total int, err error) {
+	hits, total, err := a.index.Search(query, a.PageNum, a.PageSize)
+	if err != nil || len(hits) == 0 {
+		return nil, total, err
+	}
+
+	ids := make([]int, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+	articles, err := a.db.GetArticlesByIDs(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	byID := make(map[int]*models.Article, len(articles))
+	for _, article := range articles {
+		byID[article.ID] = article
+	}
+
+	terms := search.Terms(query)
+	results = make([]*SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		// an article deleted by another instance may still be in the memory index
+		article, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+
+		highlights := make(map[string]string)
+		for field, text := range map[string]string{"title": article.Title, "desc": article.Desc, "content": article.Content} {
+			length := search.SNIPPET_LENGTH
+			if field == "title" {
+				length = 0
+			}
+			if snippet := search.Highlight(text, terms, length); snippet != "" {
+				highlights[field] = snippet
+			}
+		}
+		results = append(results, &SearchResult{Article: article, Score: hit.Score, Highlights: highlights})
+	}
+
+	return results, total, nil
+}
+
+func (a *Article) document() *search.Document {
+	return &search.Document{ID: a.ID, Title: a.Title, Desc: a.Desc, Content: a.Content}
+}
+
+func (r *record) document() *search.Document {
+	return &search.Document{ID: r.ID, Title: r.Title, Desc: r.Desc, Content: r.Content}
+}
+
+// putIndex adds an article to the search index after it was written. The database is
+// the reference, a failure is only logged
+func (s *Service) putIndex(doc *search.Document) {
+	if err := s.index.Put(doc); err != nil {
+		logging.Warn("article_service index article", doc.ID, "err:", err)
+	}
+}
+
+func (s *Service) deleteIndex(id int) {
+	if err := s.index.Delete(id); err != nil {
+		logging.Warn("article_service remove article", id, "from the index err:", err)
+	}
+}
@@ -1,12 +1,117 @@
 package auth_service
 
-import "github.com/EGGYC/go-gin-example/models"
+import (
+	"github.com/EGGYC/go-gin-example/models"
+	"github.com/EGGYC/go-gin-example/pkg/logging"
+	"github.com/EGGYC/go-gin-example/pkg/util"
+)
 
+// Service checks and edits the accounts in db
+type Service struct {
+	db *models.DB
+}
+
+func New(db *models.DB) *Service {
+	return &Service{db: db}
+}
+
+// Auth holds the parameters of an operation, Service must be set
 type Auth struct {
-	Username string
-	Password string
+	*Service
+
+	ID          int
+	Username    string
+	Password    string
+	NewPassword string
+	Role        string
+	// TokenVersion is set by Check, the tokens issued for the account carry it
+	TokenVersion int
 }
 
+// Check verifies the password of the account with ID, or with Username when ID is unset,
+// and sets ID on success. A legacy plaintext password is replaced by its hash on the first successful check
 func (a *Auth) Check() (bool, error) {
-	return models.CheckAuth(a.Username, a.Password)
+	var (
+		auth *models.Auth
+		err  error
+	)
+	if a.ID > 0 {
+		auth, err = a.db.GetAuth(a.ID)
+	} else {
+		auth, err = a.db.GetAuthByUsername(a.Username)
+	}
+	if err != nil {
+		return false, err
+	}
+	if auth == nil || !util.ComparePassword(auth.Password, a.Password) {
+		return false, nil
+	}
+
+	a.ID = auth.ID
+	a.Role = auth.Role
+	a.TokenVersion = auth.TokenVersion
+	if !util.IsPasswordHash(auth.Password) {
+		if err := a.savePassword(a.Password); err != nil {
+			// the login is still valid, try to upgrade again next time
+			logging.Warn("auth_service upgrade legacy password err:", err)
+		}
+	}
+
+	return true, nil
+}
+
+// Get returns the account with ID, it is nil when the account doesn't exist
+func (a *Auth) Get() (*models.Auth, error) {
+	return a.db.GetAuth(a.ID)
+}
+
+func (a *Auth) ExistByUsername() (bool, error) {
+	return a.db.ExistAuthByUsername(a.Username)
+}
+
+// Register adds an account with a hashed password, new accounts are readers
+// until an admin grants them another role
+func (a *Auth) Register() error {
+	hash, err := util.HashPassword(a.Password)
+	if err != nil {
+		return err
+	}
+
+	return a.db.AddAuth(a.Username, hash, models.ROLE_READER)
+}
+
+// ChangePassword replaces the password of the account with ID by NewPassword, the
+// tokens issued before stop being accepted
+func (a *Auth) ChangePassword() error {
+	hash, err := util.HashPassword(a.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	return a.db.ChangeAuthPassword(a.ID, hash)
+}
+
+// IsTokenCurrent checks a token issued with version for the account with ID wasn't
+// revoked by a password change since, it is false when the account is gone
+func (a *Auth) IsTokenCurrent(version int) (bool, error) {
+	auth, err := a.db.GetAuth(a.ID)
+	if err != nil {
+		return false, err
+	}
+
+	return auth != nil && auth.TokenVersion == version, nil
+}
+
+// EditRole gives the account with ID the role Role
+func (a *Auth) EditRole() error {
+	return a.db.EditAuthRole(a.ID, a.Role)
+}
+
+func (a *Auth) savePassword(password string) error {
+	hash, err := util.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	return a.db.EditAuthPassword(a.ID, hash)
 }
@@ -21,10 +21,14 @@ func (a *Article) GetArticleKey() string {
 	return e.CACHE_ARTICLE + "_" + strconv.Itoa(a.ID)
 }
 
+// GetArticlesKeyPrefix starts every list key, whatever the filters
+func (a *Article) GetArticlesKeyPrefix() string {
+	return e.CACHE_ARTICLE + "_LIST"
+}
+
 func (a *Article) GetArticlesKey() string {
 	keys := []string{
-		e.CACHE_ARTICLE,
-		"LIST",
+		a.GetArticlesKeyPrefix(),
 	}
 
 	if a.ID > 0 {
@@ -0,0 +1,156 @@
+package cache_service
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/EGGYC/go-gin-example/pkg/cache"
+	"github.com/EGGYC/go-gin-example/pkg/logging"
+	"github.com/EGGYC/go-gin-example/pkg/metrics"
+	"github.com/EGGYC/go-gin-example/pkg/setting"
+)
+
+// Cache reads through backend with the [cache] settings it was built with, every App has its
+// own so concurrent loads are only coalesced within one
+type Cache struct {
+	backend  cache.Cache
+	settings setting.Cache
+	// group coalesces concurrent loads of the same key
+	group singleflight.Group
+}
+
+// New returns a Cache on c, a nil c caches nothing
+func New(c cache.Cache, s *setting.Cache) *Cache {
+	if c == nil {
+		c = cache.Nop{}
+	}
+
+	return &Cache{backend: c, settings: *s}
+}
+
+// jitter spreads the expiry of entries cached at the same time
+var jitter = struct {
+	sync.Mutex
+	*rand.Rand
+}{Rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+// negative is the cached value of a key whose loader found nothing
+var negative = []byte("null")
+
+// Fetch decodes the cached value of key into v. On a miss it calls load, caches the
+// result as JSON and decodes it into v. Concurrent misses on the same key share one
+// load when [cache] Coalesce is set. A nil result from load is cached for NegativeTTL
+// and reported as not found, v is left untouched then. A failing cache counts as a
+// miss, so reads keep working on the database alone
+func (c *Cache) Fetch(key string, v interface{}, load func() (interface{}, error)) (bool, error) {
+	data, err := c.backend.Get(key)
+	if err != nil {
+		if err != cache.ErrMiss {
+			logging.Warn("cache_service.Fetch", key, "err:", err)
+		}
+		metrics.CacheMiss(cacheName(key))
+
+		data, err = c.loadOnce(key, load)
+		if err != nil {
+			return false, err
+		}
+	} else {
+		metrics.CacheHit(cacheName(key))
+	}
+
+	if bytes.Equal(data, negative) {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// cacheName labels the metrics of key by its e.CACHE_* prefix, e.g. "article"
+func cacheName(key string) string {
+	return strings.ToLower(strings.SplitN(key, "_", 2)[0])
+}
+
+// loadOnce loads and caches key, sharing the result between concurrent callers.
+// Callers get the encoded value so each one decodes its own copy
+func (c *Cache) loadOnce(key string, load func() (interface{}, error)) ([]byte, error) {
+	fn := func() (interface{}, error) {
+		value, err := load()
+		if err != nil {
+			return nil, err
+		}
+
+		ttl := ttlWithJitter(c.settings.TTL, c.settings.TTLJitter)
+		data := negative
+		if value != nil {
+			if data, err = json.Marshal(value); err != nil {
+				return nil, err
+			}
+		} else {
+			ttl = c.settings.NegativeTTL
+		}
+
+		if ttl > 0 {
+			if err := c.backend.Set(key, data, ttl); err != nil {
+				logging.Warn("cache_service.Fetch", key, "err:", err)
+			}
+		}
+
+		return data, nil
+	}
+
+	if !c.settings.Coalesce {
+		data, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		return data.([]byte), nil
+	}
+
+	data, err, _ := c.group.Do(key, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.([]byte), nil
+}
+
+// ttlWithJitter adds up to percent percent to ttl, so entries cached together
+// don't all expire, and hit the database, in the same second
+func ttlWithJitter(ttl time.Duration, percent int) time.Duration {
+	if percent <= 0 || ttl <= 0 {
+		return ttl
+	}
+
+	jitter.Lock()
+	n := jitter.Int63n(int64(ttl)*int64(percent)/100 + 1)
+	jitter.Unlock()
+
+	return ttl + time.Duration(n)
+}
+
+// Delete removes cached keys after a write, the write already succeeded so
+// errors are only logged and the stale entry expires with its TTL
+func (c *Cache) Delete(keys ...string) {
+	if err := c.backend.Delete(keys...); err != nil {
+		logging.Warn("cache_service.Delete", keys, "err:", err)
+	}
+}
+
+// DeletePrefix removes every cached key that starts with one of prefixes, such as
+// all the pages of a list
+func (c *Cache) DeletePrefix(prefixes ...string) {
+	for _, prefix := range prefixes {
+		if err := c.backend.DeletePrefix(prefix); err != nil {
+			logging.Warn("cache_service.DeletePrefix", prefix, "err:", err)
+		}
+	}
+}
@@ -17,10 +17,14 @@ type Tag struct {
 	PageSize int
 }
 
+// GetTagsKeyPrefix starts every list key, whatever the filters
+func (t *Tag) GetTagsKeyPrefix() string {
+	return e.CACHE_TAG + "_LIST"
+}
+
 func (t *Tag) GetTagsKey() string {
 	keys := []string{
-		e.CACHE_TAG,
-		"LIST",
+		t.GetTagsKeyPrefix(),
 	}
 
 	if t.Name != "" {
@@ -1,43 +1,70 @@
 package tag_service
 
 import (
-	"encoding/json"
-	"io"
 	"strconv"
 	"time"
 
-	"github.com/360EntSecGroup-Skylar/excelize"
 	"github.com/tealeg/xlsx"
 
 	"github.com/EGGYC/go-gin-example/models"
 	"github.com/EGGYC/go-gin-example/pkg/export"
 	"github.com/EGGYC/go-gin-example/pkg/file"
-	"github.com/EGGYC/go-gin-example/pkg/gredis"
 	"github.com/EGGYC/go-gin-example/pkg/logging"
+	"github.com/EGGYC/go-gin-example/pkg/setting"
+	"github.com/EGGYC/go-gin-example/service/article_service"
 	"github.com/EGGYC/go-gin-example/service/cache_service"
 )
 
+// Service reads and writes the tags in db and keeps cache consistent with them,
+// articles drops the cached articles that embed a changed tag
+type Service struct {
+	settings *setting.App
+	db       *models.DB
+	cache    *cache_service.Cache
+	articles *article_service.Service
+}
+
+// New returns the tag service, a nil cache caches nothing
+func New(s *setting.App, db *models.DB, c *cache_service.Cache, articles *article_service.Service) *Service {
+	if c == nil {
+		c = cache_service.New(nil, &setting.Cache{})
+	}
+
+	return &Service{settings: s, db: db, cache: c, articles: articles}
+}
+
+// Tag holds the parameters of an operation, Service must be set
 type Tag struct {
+	*Service
+
 	ID         int
 	Name       string
 	CreatedBy  string
 	ModifiedBy string
 	State      int
+	// KeepAuthors makes Import keep the created_by of the file instead of recording
+	// CreatedBy, only a user manager may claim a change was made by someone else
+	KeepAuthors bool
 
 	PageNum  int
 	PageSize int
 }
 
 func (t *Tag) ExistByName() (bool, error) {
-	return models.ExistTagByName(t.Name)
+	return t.db.ExistTagByName(t.Name)
 }
 
 func (t *Tag) ExistByID() (bool, error) {
-	return models.ExistTagByID(t.ID)
+	return t.db.ExistTagByID(t.ID)
 }
 
 func (t *Tag) Add() error {
-	return models.AddTag(t.Name, t.State, t.CreatedBy)
+	if err := t.db.AddTag(t.Name, t.State, t.CreatedBy); err != nil {
+		return err
+	}
+
+	t.invalidate()
+	return nil
 }
 
 func (t *Tag) Edit() error {
@@ -48,45 +75,45 @@ func (t *Tag) Edit() error {
 		data["state"] = t.State
 	}
 
-	return models.EditTag(t.ID, data)
+	if err := t.db.EditTag(t.ID, data); err != nil {
+		return err
+	}
+
+	t.invalidateArticles()
+	return nil
 }
 
 func (t *Tag) Delete() error {
-	return models.DeleteTag(t.ID)
+	if err := t.db.DeleteTag(t.ID); err != nil {
+		return err
+	}
+
+	t.invalidateArticles()
+	return nil
 }
 
 func (t *Tag) Count() (int, error) {
-	return models.GetTagTotal(t.getMaps())
+	return t.db.GetTagTotal(t.getMaps())
 }
 
 func (t *Tag) GetAll() ([]models.Tag, error) {
-	var (
-		tags, cacheTags []models.Tag
-	)
+	var tags []models.Tag
 
 	cache := cache_service.Tag{
+		Name:  t.Name,
 		State: t.State,
 
 		PageNum:  t.PageNum,
 		PageSize: t.PageSize,
 	}
 	key := cache.GetTagsKey()
-	if gredis.Exists(key) {
-		data, err := gredis.Get(key)
-		if err != nil {
-			logging.Info(err)
-		} else {
-			json.Unmarshal(data, &cacheTags)
-			return cacheTags, nil
-		}
-	}
-
-	tags, err := models.GetTags(t.PageNum, t.PageSize, t.getMaps())
+	_, err := t.cache.Fetch(key, &tags, func() (interface{}, error) {
+		return t.db.GetTags(t.PageNum, t.PageSize, t.getMaps())
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	gredis.Set(key, tags, 3600)
 	return tags, nil
 }
 
@@ -97,7 +124,7 @@ func (t *Tag) Export() (string, error) {
 	}
 
 	xlsFile := xlsx.NewFile()
-	sheet, err := xlsFile.AddSheet("标签信息")
+	sheet, err := xlsFile.AddSheet(SHEET_NAME)
 	if err != nil {
 		return "", err
 	}
@@ -131,7 +158,7 @@ func (t *Tag) Export() (string, error) {
 	time := strconv.Itoa(int(time.Now().Unix()))
 	filename := "tags-" + time + export.EXT
 
-	dirFullPath := export.GetExcelFullPath()
+	dirFullPath := export.GetExcelFullPath(t.settings)
 	err = file.IsNotExistMkDir(dirFullPath)
 	if err != nil {
 		return "", err
@@ -145,27 +172,6 @@ func (t *Tag) Export() (string, error) {
 	return filename, nil
 }
 
-func (t *Tag) Import(r io.Reader) error {
-	xlsx, err := excelize.OpenReader(r)
-	if err != nil {
-		return err
-	}
-
-	rows := xlsx.GetRows("标签信息")
-	for irow, row := range rows {
-		if irow > 0 {
-			var data []string
-			for _, cell := range row {
-				data = append(data, cell)
-			}
-
-			models.AddTag(data[1], 1, data[2])
-		}
-	}
-
-	return nil
-}
-
 func (t *Tag) getMaps() map[string]interface{} {
 	maps := make(map[string]interface{})
 	maps["deleted_on"] = 0
@@ -179,3 +185,17 @@ func (t *Tag) getMaps() map[string]interface{} {
 
 	return maps
 }
+
+// invalidate drops every cached tag list
+func (s *Service) invalidate() {
+	cache := cache_service.Tag{}
+	s.cache.DeletePrefix(cache.GetTagsKeyPrefix())
+}
+
+// invalidateArticles drops the tag lists and the cached articles that embed the tag
+func (t *Tag) invalidateArticles() {
+	t.invalidate()
+	if err := t.articles.InvalidateByTag(t.ID); err != nil {
+		logging.Warn("tag_service invalidate articles of tag", t.ID, "err:", err)
+	}
+}
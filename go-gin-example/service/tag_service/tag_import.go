@@ -0,0 +1,113 @@
+package tag_service
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/360EntSecGroup-Skylar/excelize"
+	"github.com/astaxie/beego/validation"
+	"github.com/jinzhu/gorm"
+
+	"github.com/EGGYC/go-gin-example/models"
+	"github.com/EGGYC/go-gin-example/pkg/importer"
+)
+
+// SHEET_NAME is the sheet written by Export and read by Import
+const SHEET_NAME = "标签信息"
+
+// Import reads tags from the sheet written by Export and adds them in one transaction.
+// Rows whose name already exists, in the database or earlier in the file, are skipped.
+// The tags are created by t.CreatedBy unless t.KeepAuthors is set.
+// With dryRun every row is checked the same way but the transaction is rolled back.
+// Row numbers in the report match the spreadsheet, the header is row 1
+func (t *Tag) Import(r io.Reader, dryRun bool) (*importer.Report, error) {
+	xlsx, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if xlsx.GetSheetIndex(SHEET_NAME) == 0 {
+		return nil, fmt.Errorf("sheet %s not found", SHEET_NAME)
+	}
+
+	var report *importer.Report
+	err = t.db.Transaction(func(tx *gorm.DB) error {
+		report = &importer.Report{DryRun: dryRun}
+		seen := make(map[string]bool)
+
+		for irow, row := range xlsx.GetRows(SHEET_NAME) {
+			if irow == 0 || importer.IsEmptyRow(row) {
+				continue
+			}
+
+			result := t.parseRow(irow+1, row)
+			report.Rows = append(report.Rows, result)
+			if result.Result == importer.RESULT_INVALID {
+				report.Invalid++
+				continue
+			}
+
+			exists := seen[result.Name]
+			if !exists {
+				exists, err = models.ExistTagByNameTx(tx, result.Name)
+				if err != nil {
+					return err
+				}
+			}
+			seen[result.Name] = true
+
+			if exists {
+				result.Result = importer.RESULT_SKIPPED_DUPLICATE
+				result.Reason = "tag name already exists"
+				report.Skipped++
+				continue
+			}
+
+			if err := models.AddTagTx(tx, result.Name, 1, result.CreatedBy); err != nil {
+				return fmt.Errorf("row %d: %v", result.Row, err)
+			}
+			result.Result = importer.RESULT_CREATED
+			report.Created++
+		}
+
+		if dryRun {
+			return importer.ErrDryRun
+		}
+
+		return nil
+	})
+	if err != nil && err != importer.ErrDryRun {
+		return nil, err
+	}
+
+	if !dryRun && report.Created > 0 {
+		t.invalidate()
+	}
+
+	return report, nil
+}
+
+// parseRow reads the name column, and the created_by column with KeepAuthors, and
+// validates them like AddTagForm
+func (t *Tag) parseRow(number int, row []string) *importer.Row {
+	result := &importer.Row{Row: number, CreatedBy: t.CreatedBy}
+	if len(row) > 1 {
+		result.Name = strings.TrimSpace(row[1])
+	}
+	if t.KeepAuthors && len(row) > 2 && strings.TrimSpace(row[2]) != "" {
+		result.CreatedBy = strings.TrimSpace(row[2])
+	}
+
+	valid := validation.Validation{}
+	valid.Required(result.Name, "name")
+	valid.MaxSize(result.Name, 100, "name")
+	valid.Required(result.CreatedBy, "created_by")
+	valid.MaxSize(result.CreatedBy, 100, "created_by")
+	if valid.HasErrors() {
+		result.Result = importer.RESULT_INVALID
+		// the Message of a direct check starts with an empty label, e.g. " Can not be empty"
+		result.Reason = valid.Errors[0].Key + valid.Errors[0].Message
+	}
+
+	return result
+}